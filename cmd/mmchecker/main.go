@@ -1,20 +1,412 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmchecker"
+)
+
+// Exit codes. When several files are checked, the largest code wins.
+const (
+	exitOK = 0
+	// A proof does not establish its assertion.
+	exitVerifyFailed = 1
+	// A file could not be read or parsed, or a report could not be written.
+	exitSyntaxError = 2
+	// The command line is wrong.
+	exitUsage = 3
 )
 
+const usage = `usage: mmchecker verify [flags] FILE...
+
+Commands:
+  verify    read each FILE and check every proof in it
+`
+
+var errUsage = errors.New("usage error")
+
 func main() {
-	if err := run(); err != nil {
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+
+		return exitUsage
 	}
 
-	os.Exit(0)
+	switch args[0] {
+	case "verify":
+		return runVerify(ctx, args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "mmchecker: unknown command %q\n", args[0])
+		fmt.Fprint(stderr, usage)
+
+		return exitUsage
+	}
 }
 
-func run() error {
-	fmt.Print("not yet implemented")
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
 
 	return nil
 }
+
+// outputOptions are the verify flags that only concern the command line.
+type outputOptions struct {
+	format  string
+	explain bool
+}
+
+func parseVerifyFlags(args []string, stderr io.Writer) (mmchecker.Config, outputOptions, []string, error) {
+	var cfg mmchecker.Config
+
+	var opts outputOptions
+
+	var includePath stringList
+
+	var progress time.Duration
+
+	var verbosity int
+
+	var logFormat string
+
+	var trace stringList
+
+	var ranges, globs, patterns stringList
+
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.IntVar(&verbosity, "v", 0, "log verbosity on stderr: 1 info, 2 debug, 3 proof steps, 4 tokens")
+	flags.StringVar(&logFormat, "log-format", "text", "log format on stderr: text or json")
+	flags.Var(&trace, "trace", "log everything from this subsystem: reader, toks or proof (repeatable)")
+	flags.StringVar(&cfg.BeginLabel, "begin", "", "only check proofs starting at this label")
+	flags.StringVar(&cfg.EndLabel, "end", "", "stop reading at this label")
+	flags.Var(&ranges, "range", "only check proofs in this label range, FIRST..LAST (repeatable)")
+	flags.Var(&globs, "glob", "only check proofs whose label matches this shell pattern (repeatable)")
+	flags.Var(&patterns, "regexp", "only check proofs whose label matches this regular expression (repeatable)")
+	flags.Var((*stringList)(&cfg.Selection.Labels), "label", "only check the proof of this label (repeatable)")
+	flags.BoolVar(&cfg.Selection.Dependencies, "deps", false, "also check the theorems that selected proofs depend on")
+	flags.Var(&includePath, "I", "directory to search for $[ $] files (repeatable)")
+	flags.BoolVar(&cfg.ParseOnly, "parse-only", false, "read the database without checking proofs")
+	flags.DurationVar(&progress, "progress", 0, "print a progress line to stderr at this interval (0 means never)")
+	flags.DurationVar(&cfg.ProofTimeout, "proof-timeout", 0, "maximum time to verify a single proof (0 means no limit)")
+	flags.IntVar(&cfg.Limits.MaxTokens, "max-tokens", 0, "maximum number of tokens read (0 means no limit)")
+	flags.IntVar(&cfg.Limits.MaxStatementLength, "max-statement", 0, "maximum number of symbols in a statement (0 means no limit)")
+	flags.IntVar(&cfg.Limits.MaxLineLength, "max-line", 0, "maximum number of bytes in a line (0 means no limit)")
+	flags.IntVar(&cfg.Limits.MaxProofLength, "max-proof", 0, "maximum number of labels in a proof (0 means no limit)")
+	flags.IntVar(&cfg.Limits.MaxStackDepth, "max-stack", 0, "maximum depth of the proof stack (0 means no limit)")
+	flags.IntVar(&cfg.Limits.MaxSubstitutionLength, "max-subst", 0, "maximum number of symbols in a statement built by substitution (0 means no limit)")
+	flags.IntVar(&cfg.Limits.MaxIncludes, "max-includes", 0, "maximum number of included files (0 means no limit)")
+	flags.IntVar(&cfg.Limits.MaxIncludeDepth, "max-include-depth", 0, "maximum nesting of included files (0 means no limit)")
+	flags.StringVar(&opts.format, "format", "text", "output format on stdout: text, jsonl, junit, sarif or quickfix")
+	flags.BoolVar(&opts.explain, "explain", false, "explain each failed proof step on stderr")
+	flags.BoolVar(&cfg.Recover, "keep-going", false, "report every error instead of stopping at the first")
+	flags.IntVar(&cfg.MaxErrors, "max-errors", 0, "with -keep-going, stop after this many errors (0 means no limit)")
+	flags.BoolVar(&cfg.AllowIncomplete, "allow-incomplete", false, "report proofs with unknown steps (?) as incomplete instead of failing")
+
+	if err := flags.Parse(args); err != nil {
+		return cfg, opts, nil, errUsage
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "mmchecker verify: no files given")
+
+		return cfg, opts, nil, errUsage
+	}
+
+	if _, ok := writers[opts.format]; !ok && opts.format != "text" {
+		fmt.Fprintf(stderr, "mmchecker verify: unknown format %q\n", opts.format)
+
+		return cfg, opts, nil, errUsage
+	}
+
+	cfg.IncludePath = includePath
+
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			fmt.Fprintf(stderr, "mmchecker verify: glob %q: %v\n", glob, err)
+
+			return cfg, opts, nil, errUsage
+		}
+	}
+
+	cfg.Selection.Globs = globs
+
+	for _, r := range ranges {
+		labelRange, err := mmchecker.ParseLabelRange(r)
+		if err != nil {
+			fmt.Fprintf(stderr, "mmchecker verify: %v\n", err)
+
+			return cfg, opts, nil, errUsage
+		}
+
+		cfg.Selection.Ranges = append(cfg.Selection.Ranges, labelRange)
+	}
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			fmt.Fprintf(stderr, "mmchecker verify: %v\n", err)
+
+			return cfg, opts, nil, errUsage
+		}
+
+		cfg.Selection.Patterns = append(cfg.Selection.Patterns, re)
+	}
+
+	logger, err := newLogger(stderr, logFormat, verbosity)
+	if err != nil {
+		fmt.Fprintf(stderr, "mmchecker verify: %v\n", err)
+
+		return cfg, opts, nil, errUsage
+	}
+
+	cfg.Logger = logger
+
+	for _, subsystem := range trace {
+		switch subsystem {
+		case mmchecker.SubsystemReader, mmchecker.SubsystemToks, mmchecker.SubsystemProof:
+			if cfg.LogLevels == nil {
+				cfg.LogLevels = map[string]slog.Level{}
+			}
+
+			cfg.LogLevels[subsystem] = mmchecker.LevelTokens
+		default:
+			fmt.Fprintf(stderr, "mmchecker verify: unknown subsystem %q\n", subsystem)
+
+			return cfg, opts, nil, errUsage
+		}
+	}
+
+	if progress > 0 {
+		cfg.ProgressInterval = progress
+		cfg.Progress = func(p mmchecker.Progress) {
+			printProgress(stderr, p)
+		}
+	}
+
+	return cfg, opts, flags.Args(), nil
+}
+
+// writers for the -format flag, besides the default text.
+var writers = map[string]func(io.Writer, ...*mmchecker.Report) error{
+	"jsonl":    mmchecker.WriteJSONLines,
+	"junit":    mmchecker.WriteJUnit,
+	"sarif":    mmchecker.WriteSARIF,
+	"quickfix": mmchecker.WriteQuickfix,
+}
+
+func runVerify(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	cfg, opts, files, err := parseVerifyFlags(args, stderr)
+	if err != nil {
+		return exitUsage
+	}
+
+	code := exitOK
+
+	reports := make([]*mmchecker.Report, 0, len(files))
+
+	for _, file := range files {
+		report, fileCode := verifyOne(ctx, file, cfg, opts.format == "text", opts.explain, stdout, stderr)
+		if fileCode > code {
+			code = fileCode
+		}
+
+		if report != nil {
+			reports = append(reports, report)
+		}
+	}
+
+	if write, ok := writers[opts.format]; ok {
+		if err := write(stdout, reports...); err != nil {
+			fmt.Fprintf(stderr, "mmchecker verify: %v\n", err)
+
+			if code < exitSyntaxError {
+				code = exitSyntaxError
+			}
+		}
+	}
+
+	return code
+}
+
+// verifyOne checks a file and reports errors on stderr, explaining failed proofs
+// if explain is set, and success on stdout if text is set.
+func verifyOne(
+	ctx context.Context,
+	file string,
+	cfg mmchecker.Config,
+	text bool,
+	explain bool,
+	stdout io.Writer,
+	stderr io.Writer,
+) (*mmchecker.Report, int) {
+	report, err := mmchecker.ValidateWithConfig(ctx, file, "", cfg)
+	if err == nil {
+		if !text {
+			return report, exitOK
+		}
+
+		if report.Totals.Incomplete == 0 {
+			fmt.Fprintf(
+				stdout,
+				"%s: ok (%d statements, %d proofs valid, %d skipped)\n",
+				file,
+				report.Totals.Statements,
+				report.Totals.Valid,
+				report.Totals.Skipped,
+			)
+
+			return report, exitOK
+		}
+
+		printIncomplete(stderr, file, report)
+		fmt.Fprintf(
+			stdout,
+			"%s: ok (%d statements, %d proofs valid, %d incomplete, %d skipped)\n",
+			file,
+			report.Totals.Statements,
+			report.Totals.Valid,
+			report.Totals.Incomplete,
+			report.Totals.Skipped,
+		)
+
+		return report, exitOK
+	}
+
+	if report == nil {
+		// The file was never read, so the arguments were at fault.
+		fmt.Fprintf(stderr, "mmchecker verify: %q: %v\n", file, err)
+
+		return nil, exitUsage
+	}
+
+	code := exitOK
+
+	for _, d := range report.Diagnostics() {
+		errCode := printError(stderr, file, d.Err)
+		if errCode > code {
+			code = errCode
+		}
+
+		if explain && d.Explanation != "" {
+			fmt.Fprint(stderr, d.Explanation)
+		}
+	}
+
+	if len(report.Errors) > 1 {
+		fmt.Fprintf(stderr, "%s: %d errors\n", file, len(report.Errors))
+	}
+
+	return report, code
+}
+
+// printError reports one diagnostic and returns the exit code it calls for.
+func printError(stderr io.Writer, file string, err error) int {
+	_, canceled := mmchecker.CanceledAt(err)
+
+	switch {
+	case canceled:
+		fmt.Fprintf(stderr, "%s: interrupted: %v\n", file, err)
+
+		return exitSyntaxError
+	case mmchecker.IsVerificationFailure(err):
+		fmt.Fprintf(stderr, "%s: verification failed: %v\n", file, err)
+
+		return exitVerifyFailed
+	default:
+		fmt.Fprintf(stderr, "%s: %v\n", file, err)
+
+		return exitSyntaxError
+	}
+}
+
+// printIncomplete lists the incomplete proofs of a report, which -allow-incomplete
+// keeps from being errors.
+func printIncomplete(stderr io.Writer, file string, report *mmchecker.Report) {
+	for _, stmt := range report.Statements {
+		if stmt.Outcome != mmchecker.OutcomeIncomplete {
+			continue
+		}
+
+		pos := stmt.Position
+		if pos.File == "" {
+			pos.File = file
+		}
+
+		fmt.Fprintf(stderr, "%s: warning: %v\n", pos, stmt.Err)
+	}
+}
+
+// newLogger maps the numeric -v flag onto slog levels.
+func newLogger(w io.Writer, format string, verbosity int) (*slog.Logger, error) {
+	level := slog.LevelWarn
+
+	switch {
+	case verbosity >= 4:
+		level = mmchecker.LevelTokens
+	case verbosity == 3:
+		level = mmchecker.LevelTrace
+	case verbosity == 2:
+		level = slog.LevelDebug
+	case verbosity == 1:
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// printProgress writes a one-line heartbeat.
+func printProgress(w io.Writer, p mmchecker.Progress) {
+	percent := 100.0
+	if p.BytesTotal > 0 {
+		percent = 100 * float64(p.BytesRead) / float64(p.BytesTotal)
+	}
+
+	fmt.Fprintf(
+		w,
+		"progress: %.1f%% of %d bytes, %d statements, %d proofs, at %q\n",
+		percent,
+		p.BytesTotal,
+		p.Statements,
+		p.ProofsVerified,
+		p.Label,
+	)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const validDatabase = `
+$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+idi.1 $e |- ph $.
+idi $p |- ph $= ( ) B $.
+`
+
+// TestRun tests that exit codes come from the verification result.
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	valid := write("valid.mm", validDatabase)
+	badProof := write("bad-proof.mm", `
+$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+idi.1 $e |- ph $.
+idi $p |- ph $= ( ) A $.
+`)
+	badSyntax := write("bad-syntax.mm", "$c |- wff")
+	incomplete := write("incomplete.mm", `
+$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+idi.1 $e |- ph $.
+idi $p |- ph $= ? $.
+`)
+
+	cases := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no arguments", args: nil, code: exitUsage},
+		{name: "unknown command", args: []string{"frobnicate"}, code: exitUsage},
+		{name: "no files", args: []string{"verify"}, code: exitUsage},
+		{name: "bad flag", args: []string{"verify", "-nope", valid}, code: exitUsage},
+		{name: "valid", args: []string{"verify", valid}, code: exitOK},
+		{name: "bad proof", args: []string{"verify", badProof}, code: exitVerifyFailed},
+		{name: "bad proof parse only", args: []string{"verify", "-parse-only", badProof}, code: exitOK},
+		{name: "bad syntax", args: []string{"verify", badSyntax}, code: exitSyntaxError},
+		{name: "empty path", args: []string{"verify", ""}, code: exitUsage},
+		{name: "missing file", args: []string{"verify", filepath.Join(dir, "missing.mm")}, code: exitSyntaxError},
+		{name: "worst code wins", args: []string{"verify", valid, badSyntax, badProof}, code: exitSyntaxError},
+		{name: "json logs", args: []string{"verify", "-v", "3", "-log-format", "json", valid}, code: exitOK},
+		{name: "trace subsystem", args: []string{"verify", "-trace", "proof", valid}, code: exitOK},
+		{name: "bad subsystem", args: []string{"verify", "-trace", "nope", valid}, code: exitUsage},
+		{name: "bad log format", args: []string{"verify", "-log-format", "xml", valid}, code: exitUsage},
+		{name: "progress", args: []string{"verify", "-progress", "1h", valid}, code: exitOK},
+		{name: "end label", args: []string{"verify", "-end", "idi", badProof}, code: exitOK},
+		{name: "keep going", args: []string{"verify", "-keep-going", badProof}, code: exitVerifyFailed},
+		{name: "bad max errors", args: []string{"verify", "-max-errors", "x", valid}, code: exitUsage},
+		{name: "jsonl", args: []string{"verify", "-format", "jsonl", valid}, code: exitOK},
+		{name: "junit", args: []string{"verify", "-format", "junit", valid, badProof}, code: exitVerifyFailed},
+		{name: "sarif", args: []string{"verify", "-format", "sarif", badSyntax}, code: exitSyntaxError},
+		{name: "explain", args: []string{"verify", "-explain", badProof}, code: exitVerifyFailed},
+		{name: "bad format", args: []string{"verify", "-format", "xml", valid}, code: exitUsage},
+		{name: "limits", args: []string{"verify", "-max-tokens", "100", "-max-stack", "10", valid}, code: exitOK},
+		{name: "token limit", args: []string{"verify", "-max-tokens", "3", valid}, code: exitSyntaxError},
+		{name: "line limit", args: []string{"verify", "-max-line", "5", valid}, code: exitSyntaxError},
+		{name: "unselected bad proof", args: []string{"verify", "-label", "other", badProof}, code: exitOK},
+		{name: "glob", args: []string{"verify", "-glob", "id*", badProof}, code: exitVerifyFailed},
+		{name: "range", args: []string{"verify", "-range", "idi..", "-deps", badProof}, code: exitVerifyFailed},
+		{name: "regexp", args: []string{"verify", "-regexp", "^x", badProof}, code: exitOK},
+		{name: "bad range", args: []string{"verify", "-range", "idi", valid}, code: exitUsage},
+		{name: "bad glob", args: []string{"verify", "-glob", "[", valid}, code: exitUsage},
+		{name: "bad regexp", args: []string{"verify", "-regexp", "(", valid}, code: exitUsage},
+		{name: "incomplete", args: []string{"verify", incomplete}, code: exitVerifyFailed},
+		{name: "allow incomplete", args: []string{"verify", "-allow-incomplete", incomplete}, code: exitOK},
+	}
+
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+
+			if code := run(context.Background(), tt.args, &stdout, &stderr); code != tt.code {
+				t.Errorf("exit code %d, want %d (stderr: %s)", code, tt.code, stderr.String())
+			}
+		})
+	}
+}
+
+// TestRun_KeepGoing tests that every error is reported.
+func TestRun_KeepGoing(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "errors.mm")
+
+	err := os.WriteFile(path, []byte(`
+$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+bad1 $p wff ph $= wph wph $.
+bad2 $a |- ps $.
+bad3 $p wff ph $= wph wph $.
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	if code := run(context.Background(), []string{"verify", "-keep-going", path}, &stdout, &stderr); code != exitSyntaxError {
+		t.Errorf("exit code %d, want %d", code, exitSyntaxError)
+	}
+
+	for _, want := range []string{"bad1", "bad2", "bad3", "3 errors"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr does not mention %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+// TestRun_Quickfix tests that -format quickfix puts one line per error on stdout.
+func TestRun_Quickfix(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "bad.mm")
+
+	if err := os.WriteFile(path, []byte("$c |- $.\nax $a |- x $.\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	run(context.Background(), []string{"verify", "-format", "quickfix", path}, &stdout, &stderr)
+
+	want := path + `:2:10: reading statement in $a: Token "x" is not an active symbol` + "\n"
+	if stdout.String() != want {
+		t.Errorf("got %q, want %q", stdout.String(), want)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// TestRun_WriteFailure tests that a report that cannot be written is an I/O
+// error, not a usage error.
+func TestRun_WriteFailure(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "valid.mm")
+
+	if err := os.WriteFile(path, []byte(validDatabase), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+
+	if code := run(context.Background(), []string{"verify", "-format", "jsonl", path}, failingWriter{}, &stderr); code != exitSyntaxError {
+		t.Errorf("exit code %d, want %d (stderr: %s)", code, exitSyntaxError, stderr.String())
+	}
+}
+
+// TestRun_Interrupted tests that a run stopped by its context, whether
+// canceled or past its deadline, is reported as interrupted.
+func TestRun_Interrupted(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "valid.mm")
+
+	if err := os.WriteFile(path, []byte(validDatabase), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Unix(0, 0))
+	defer cancel()
+
+	for _, ctx := range []context.Context{canceled, expired} {
+		var stdout, stderr bytes.Buffer
+
+		if code := run(ctx, []string{"verify", path}, &stdout, &stderr); code != exitSyntaxError {
+			t.Errorf("exit code %d, want %d", code, exitSyntaxError)
+		}
+
+		if !strings.Contains(stderr.String(), "interrupted") {
+			t.Errorf("stderr does not say the run was interrupted:\n%s", stderr.String())
+		}
+	}
+}
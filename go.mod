@@ -1,5 +1,5 @@
 module github.com/gregory-nisbet/mmchecker
 
-go 1.19
+go 1.21
 
-require github.com/google/go-cmp v0.6.0 // indirect
+require github.com/google/go-cmp v0.6.0
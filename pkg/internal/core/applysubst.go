@@ -1,7 +1,5 @@
 package core
 
-import "fmt"
-
 // This is a non-capture-avoiding substitution because that's what
 // metamath is based on.
 func ApplySubst(stmt Stmt, subst map[string]Stmt) Stmt {
@@ -14,6 +12,5 @@ func ApplySubst(stmt Stmt, subst map[string]Stmt) Stmt {
 			result = append(result, tok)
 		}
 	}
-	Vprint(20, "Applying subst", fmt.Sprintf("%v", subst), "to stmt", fmt.Sprintf("%v", stmt), ":", fmt.Sprintf("%v", result))
 	return result
 }
@@ -4,14 +4,14 @@ import "fmt"
 
 type Assertion struct {
 	Dvs map[Dv]struct{}
-	F   []Fhyp
-	E   []Ehyp
-	S   Stmt
+	// The mandatory hypotheses, in the order a proof supplies them.
+	Hyps []Hyp
+	S    Stmt
 }
 
 func (assertion *Assertion) String() string {
 	if assertion == nil {
 		return "<nil>"
 	}
-	return fmt.Sprintf("Assertion %v %v %v %v", assertion.Dvs, assertion.F, assertion.E, assertion.S)
+	return fmt.Sprintf("Assertion %v %v %v", assertion.Dvs, assertion.Hyps, assertion.S)
 }
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// CancelError is returned when reading or verification stops because the
+// context passed to ReadContext is done. It unwraps to context.Canceled or
+// context.DeadlineExceeded.
+type CancelError struct {
+	// The label being processed when work stopped. Empty if no label had
+	// been read yet.
+	Label Label
+	err   error
+}
+
+func (c CancelError) Error() string {
+	if c.Label == "" {
+		return fmt.Sprintf("stopped before the first label: %s", c.err.Error())
+	}
+	return fmt.Sprintf("stopped while processing %q: %s", c.Label, c.err.Error())
+}
+
+func (c CancelError) Unwrap() error {
+	return c.err
+}
+
+func (c CancelError) Is(target error) bool {
+	return target == mmerror.Canceled
+}
+
+func (c CancelError) As(target any) bool {
+	return asPublic(target, mmerror.Canceled, c.Label, Pos{}, Pos{}, c.Error(), c.err)
+}
+
+func AsCancelError(e error) *CancelError {
+	var c CancelError
+	if errors.As(e, &c) {
+		return &c
+	}
+	return nil
+}
+
+// checkCtx returns a CancelError if the read should stop, or an MMError if
+// the current proof has run past ProofTimeout. It is called once per
+// statement and once per proof step.
+func (self *MM) checkCtx() error {
+	if self.ctx != nil {
+		if err := self.ctx.Err(); err != nil {
+			return CancelError{Label: self.current, err: err}
+		}
+	}
+	if self.proofCtx != nil {
+		if err := self.proofCtx.Err(); err != nil {
+			return MMError{
+				Code: mmerror.LimitExceeded,
+				err:  fmt.Errorf("proof time limit of %v: %w", self.ProofTimeout, err),
+			}
+		}
+	}
+	return nil
+}
+
+// verifyWithDeadline runs Verify under ProofTimeout, if one is set.
+func (self *MM) verifyWithDeadline(assertion Assertion, proof []string) error {
+	if self.ProofTimeout <= 0 {
+		return self.Verify(assertion.Hyps, assertion.S, proof)
+	}
+	parent := self.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, self.ProofTimeout)
+	defer cancel()
+	self.proofCtx = ctx
+	defer func() { self.proofCtx = nil }()
+	return self.Verify(assertion.Hyps, assertion.S, proof)
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+func TestReadContext_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mm := NewMM(nil)
+	toks, err := NewToks("", ToTokens(visitorDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mm.ReadContext(ctx, toks)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if c := AsCancelError(err); c == nil || c.Label != "" {
+		t.Errorf("unexpected cancel error %v", err)
+	}
+}
+
+type cancellingVisitor struct {
+	NopVisitor
+	cancel context.CancelFunc
+}
+
+func (c cancellingVisitor) OnHypothesis(label Label, _ string, _ Stmt) error {
+	if label == "idi.1" {
+		c.cancel()
+	}
+	return nil
+}
+
+func TestReadContext_CancelledMidway(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mm := NewMMWithOptions(Options{Visitor: cancellingVisitor{cancel: cancel}})
+	toks, err := NewToks("", ToTokens(visitorDatabase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mm.ReadContext(ctx, toks)
+	c := AsCancelError(err)
+	if c == nil {
+		t.Fatalf("expected CancelError, got %v", err)
+	}
+	if c.Label != "idi.1" {
+		t.Errorf("unexpected label %q", c.Label)
+	}
+	if AsVerifyError(err) != nil {
+		t.Error("cancellation should not look like a verification failure")
+	}
+}
+
+func TestReadContext_ProofTimeout(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMMWithOptions(Options{ProofTimeout: time.Nanosecond})
+	err := mm.CheckString(visitorDatabase)
+	if AsCancelError(err) != nil || !errors.Is(err, mmerror.LimitExceeded) {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+	if m := AsMMError(err); m.Label != "idi" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if mm.Results[len(mm.Results)-1].Checked {
+		t.Error("a proof that timed out counts as checked")
+	}
+	if !strings.Contains(err.Error(), "proof time limit") {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
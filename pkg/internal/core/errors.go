@@ -2,7 +2,10 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"io"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
 )
 
 var EOF error = io.EOF
@@ -16,13 +19,25 @@ type IOError struct {
 }
 
 func (i IOError) Error() string {
-	msg := i.Error()
+	msg := i.err.Error()
 	if msg == "" {
 		panic(`IOError wrapped error is stringifies to ""`)
 	}
 	return msg
 }
 
+func (i IOError) Unwrap() error {
+	return i.err
+}
+
+func (i IOError) Is(target error) bool {
+	return target == mmerror.IO
+}
+
+func (i IOError) As(target any) bool {
+	return asPublic(target, mmerror.IO, "", Pos{}, Pos{}, i.Error(), i.err)
+}
+
 func AsIOError(e error) *IOError {
 	var i IOError
 	if errors.As(e, &i) {
@@ -33,16 +48,68 @@ func AsIOError(e error) *IOError {
 
 type MMError struct {
 	err error
+	// Code is the kind of problem. Errors that only add context to the one
+	// they wrap leave it empty.
+	Code mmerror.Code
+	// Where the problem was found: the offending token if known, otherwise
+	// the start of the statement. The zero Pos means unknown.
+	Pos Pos
+	// The label of the statement being read, if it has one, and where the
+	// label starts.
+	Label    Label
+	LabelPos Pos
+	// located is set on the MMError that Read wraps around a failed
+	// statement. Only that one prints the positions, so that nested
+	// errors do not repeat them.
+	located bool
+	// note is extra information found after the error, such as where a
+	// missing label turned up.
+	note string
 }
 
 func (i MMError) Error() string {
-	msg := i.Error()
+	msg := i.err.Error()
 	if msg == "" {
 		panic(`MMError stringifies to ""`)
 	}
+	if i.note != "" {
+		msg += " (" + i.note + ")"
+	}
+	if !i.located {
+		return msg
+	}
+	if i.Pos.IsValid() {
+		msg = i.Pos.String() + ": " + msg
+	}
+	if i.Label != "" {
+		msg += fmt.Sprintf(" (in statement %q starting at %s)", i.Label, i.LabelPos)
+	}
 	return msg
 }
 
+func (i MMError) Unwrap() error {
+	return i.err
+}
+
+// Is reports whether target is the Code of i.
+func (i MMError) Is(target error) bool {
+	return i.Code != "" && target == i.Code
+}
+
+// As converts i to an *mmerror.Error, taking the code from the errors it
+// wraps if i has none.
+func (i MMError) As(target any) bool {
+	return asPublic(target, codeOf(i), i.Label, i.Pos, i.LabelPos, i.Message(), i.err)
+}
+
+// Message is the error without the positions that Error adds.
+func (i MMError) Message() string {
+	if i.note != "" {
+		return i.err.Error() + " (" + i.note + ")"
+	}
+	return i.err.Error()
+}
+
 func AsMMError(e error) *MMError {
 	var m MMError
 	if errors.As(e, &m) {
@@ -50,3 +117,108 @@ func AsMMError(e error) *MMError {
 	}
 	return nil
 }
+
+// VerifyError is returned when a $p statement parses correctly but its
+// proof does not establish the assertion.
+type VerifyError struct {
+	Label Label
+	err   error
+}
+
+func (v VerifyError) Error() string {
+	return fmt.Sprintf("%s: %s", v.Label, v.err.Error())
+}
+
+func (v VerifyError) Unwrap() error {
+	return v.err
+}
+
+func AsVerifyError(e error) *VerifyError {
+	var v VerifyError
+	if errors.As(e, &v) {
+		return &v
+	}
+	return nil
+}
+
+// locate wraps the error from a failed statement in an MMError that knows
+// where the statement and its label are. The most precise position already
+// recorded further down the chain wins over stmtPos. End of file,
+// cancellations and errors that are already located are returned unchanged.
+func locate(err error, stmtPos Pos, label *Label, labelPos Pos) error {
+	if err == nil || IsEOF(err) || AsCancelError(err) != nil {
+		return err
+	}
+	var outer MMError
+	if errors.As(err, &outer) && outer.located {
+		return err
+	}
+	out := MMError{err: err, Code: codeOf(err), Pos: stmtPos, located: true}
+	if inner := AsMMError(err); inner != nil && inner.Pos.IsValid() {
+		out.Pos = inner.Pos
+	}
+	if label != nil {
+		out.Label = *label
+		out.LabelPos = labelPos
+	} else if inner := AsMMError(err); inner != nil {
+		out.Label = inner.Label
+		out.LabelPos = inner.LabelPos
+	}
+	return out
+}
+
+// errTokenLimit is the one limit that is not recoverable: skipping a
+// statement would only read more tokens.
+var errTokenLimit = errors.New("read more tokens than the limit")
+
+// errLineLimit is a line of a file over MaxLineLength. The line is skipped,
+// so it can be recovered from between statements as well as within one.
+var errLineLimit = errors.New("line is longer than the limit")
+
+// recoverable reports whether Read may skip the statement that caused err
+// and carry on. Cancellation, I/O failures, errors from the Visitor and
+// hitting MaxErrors or MaxTokens all end the run.
+func recoverable(err error) bool {
+	if AsCancelError(err) != nil || AsIOError(err) != nil || errors.Is(err, mmerror.TooManyErrors) || errors.Is(err, errTokenLimit) {
+		return false
+	}
+	var v visitorError
+	return !errors.As(err, &v)
+}
+
+// codeOf returns the code of the outermost error in the chain of err that
+// has one, or mmerror.Unknown.
+func codeOf(err error) mmerror.Code {
+	for err != nil {
+		switch e := err.(type) {
+		case MMError:
+			if e.Code != "" {
+				return e.Code
+			}
+		case CancelError:
+			return mmerror.Canceled
+		case IOError:
+			return mmerror.IO
+		}
+		err = errors.Unwrap(err)
+	}
+	return mmerror.Unknown
+}
+
+// asPublic implements As for the errors of this package: if target is an
+// **mmerror.Error, it is set to a new one made from the other arguments.
+func asPublic(target any, code mmerror.Code, label Label, pos Pos, labelPos Pos, msg string, err error) bool {
+	t, ok := target.(**mmerror.Error)
+	if !ok {
+		return false
+	}
+	*t = &mmerror.Error{
+		Code:          code,
+		Label:         string(label),
+		Position:      pos.Position(),
+		LabelPosition: labelPos.Position(),
+		Message:       msg,
+		Err:           err,
+	}
+	return true
+}
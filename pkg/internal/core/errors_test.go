@@ -27,8 +27,46 @@ func TestIOError(t *testing.T) {
 func TestMMError(t *testing.T) {
 	t.Parallel()
 
-	e := MMError{errors.New("hi")}
+	e := MMError{err: errors.New("hi")}
 	if AsMMError(e) == nil {
 		t.Error("AsMMError failed")
 	}
 }
+
+func TestVerifyError(t *testing.T) {
+	t.Parallel()
+
+	e := fmt.Errorf("wrapped: %w", VerifyError{Label: "idi", err: MMError{err: errors.New("hi")}})
+	v := AsVerifyError(e)
+	if v == nil {
+		t.Fatal("AsVerifyError failed")
+	}
+	if v.Error() != "idi: hi" {
+		t.Errorf("unexpected message %q", v.Error())
+	}
+	if AsMMError(e) == nil {
+		t.Error("VerifyError should unwrap to MMError")
+	}
+}
+
+func TestLocatedMMError(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMM(nil)
+	toks := NewStringToks("$c |- $.\nax  $a |- x $.\n")
+	err := mm.Read(toks)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	want := `2:11: reading statement in $a: Token "x" is not an active symbol (in statement "ax" starting at 2:1)`
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+	m := AsMMError(err)
+	if m == nil {
+		t.Fatal("AsMMError failed")
+	}
+	if m.Pos != (Pos{Line: 2, Col: 11}) || m.Label != "ax" || m.LabelPos != (Pos{Line: 2, Col: 1}) {
+		t.Errorf("unexpected positions %+v", m)
+	}
+}
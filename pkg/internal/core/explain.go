@@ -0,0 +1,207 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProofFailure is returned when a proof step, or the end of a proof, does
+// not check. It keeps enough of the verifier's state to explain why.
+type ProofFailure struct {
+	// Step counts from 1 and Label is the label used at that step. Step is
+	// zero when the failure was found after the last step.
+	Step  int
+	Label Label
+	// The proof stack, bottom first, before the step was applied.
+	Stack []Stmt
+	// The substitution built from the $f hypotheses of the assertion used
+	// at the step, as far as it got.
+	Subst map[string]Stmt
+	// The statement that was needed and the one that was found, when the
+	// failure is a mismatch.
+	Expected Stmt
+	Actual   Stmt
+	// The $d pair of the assertion that was violated, and the variables of
+	// the substituted expressions that are not known to be disjoint.
+	Disjoint     *Dv
+	DisjointVars [2]string
+	err          error
+}
+
+func (f ProofFailure) Error() string {
+	return f.err.Error()
+}
+
+func (f ProofFailure) Unwrap() error {
+	return f.err
+}
+
+func AsProofFailure(e error) *ProofFailure {
+	var f ProofFailure
+	if errors.As(e, &f) {
+		return &f
+	}
+	return nil
+}
+
+// Explain describes the failure over several lines: the step, the stack,
+// the substitution, a diff of the mismatched statements and the violated
+// $d pair.
+func (f ProofFailure) Explain() string {
+	var b strings.Builder
+	if f.Step > 0 {
+		fmt.Fprintf(&b, "step %d (%s): %s\n", f.Step, f.Label, f.err)
+	} else {
+		fmt.Fprintf(&b, "end of proof: %s\n", f.err)
+	}
+	b.WriteString("stack (bottom first):\n")
+	if len(f.Stack) == 0 {
+		b.WriteString("  (empty)\n")
+	}
+	for i, stmt := range f.Stack {
+		fmt.Fprintf(&b, "  %d: %s\n", i, unknownAsQuestionMark(stmt))
+	}
+	if len(f.Subst) > 0 {
+		b.WriteString("substitution:\n")
+		vars := make([]string, 0, len(f.Subst))
+		for v := range f.Subst {
+			vars = append(vars, v)
+		}
+		sort.Strings(vars)
+		for _, v := range vars {
+			fmt.Fprintf(&b, "  %s := %s\n", v, unknownAsQuestionMark(f.Subst[v]))
+		}
+	}
+	if f.Expected != nil || f.Actual != nil {
+		b.WriteString(StmtDiff(f.Expected, f.Actual))
+	}
+	if f.Disjoint != nil {
+		x, y := f.Disjoint.First, f.Disjoint.Second
+		fmt.Fprintf(&b, "disjoint: $d %s %s is violated: %s := %s, %s := %s, but %s and %s are not disjoint\n",
+			x, y, x, f.Subst[x], y, f.Subst[y], f.DisjointVars[0], f.DisjointVars[1])
+	}
+	return b.String()
+}
+
+// atStep records where in the proof a ProofFailure happened. Other errors
+// are returned unchanged.
+func atStep(err error, step int, label Label) error {
+	var f ProofFailure
+	if !errors.As(err, &f) || f.Step != 0 {
+		return err
+	}
+	f.Step = step
+	f.Label = label
+	return f
+}
+
+// StmtDiff lines up two statements symbol by symbol and marks the symbols
+// that differ:
+//
+//	expected: |- ( ph -> ps )
+//	actual:   |- ( ph -> ch )
+//	                     ^^
+func StmtDiff(expected Stmt, actual Stmt) string {
+	var want, got, mark []string
+	for _, op := range diffStmts(expected, actual) {
+		width := len(op.want)
+		if len(op.got) > width {
+			width = len(op.got)
+		}
+		want = append(want, pad(op.want, width))
+		got = append(got, pad(op.got, width))
+		if op.want == op.got {
+			mark = append(mark, strings.Repeat(" ", width))
+		} else {
+			mark = append(mark, strings.Repeat("^", width))
+		}
+	}
+	return fmt.Sprintf("expected: %s\nactual:   %s\n          %s\n",
+		strings.TrimRight(strings.Join(want, " "), " "),
+		strings.TrimRight(strings.Join(got, " "), " "),
+		strings.TrimRight(strings.Join(mark, " "), " "))
+}
+
+// diffOp is one column of StmtDiff. A symbol missing from one side is "".
+type diffOp struct {
+	want string
+	got  string
+}
+
+// diffStmts aligns a and b symbol for symbol if they have the same length,
+// which is what a wrong substitution usually gives. Otherwise it aligns
+// them along their longest common subsequence, pairing up the symbols
+// between two common ones where it can.
+func diffStmts(a Stmt, b Stmt) []diffOp {
+	if len(a) == len(b) {
+		out := make([]diffOp, len(a))
+		for i := range a {
+			out[i] = diffOp{want: a[i], got: b[i]}
+		}
+		return out
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var out []diffOp
+	var removed, added []string
+	flush := func() {
+		for len(removed) > 0 || len(added) > 0 {
+			var op diffOp
+			if len(removed) > 0 {
+				op.want, removed = removed[0], removed[1:]
+			}
+			if len(added) > 0 {
+				op.got, added = added[0], added[1:]
+			}
+			out = append(out, op)
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			out = append(out, diffOp{want: a[i], got: b[j]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, a[i])
+			i++
+		default:
+			added = append(added, b[j])
+			j++
+		}
+	}
+	flush()
+	return out
+}
+
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// unknownAsQuestionMark shows the result of an unknown proof step as the ? it
+// was written with.
+func unknownAsQuestionMark(stmt Stmt) Stmt {
+	if stmt == nil {
+		return Stmt{"?"}
+	}
+	return stmt
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStmtDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		expected Stmt
+		actual   Stmt
+		want     string
+	}{
+		{
+			name:     "replaced",
+			expected: Stmt{"|-", "(", "ph", "->", "ps", ")"},
+			actual:   Stmt{"|-", "(", "ph", "->", "chi", ")"},
+			want: "expected: |- ( ph -> ps  )\n" +
+				"actual:   |- ( ph -> chi )\n" +
+				"                     ^^^\n",
+		},
+		{
+			name:     "missing",
+			expected: Stmt{"|-", "ph", "ps"},
+			actual:   Stmt{"|-", "ps"},
+			want: "expected: |- ph ps\n" +
+				"actual:   |-    ps\n" +
+				"             ^^\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := StmtDiff(tt.expected, tt.actual); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProofFailure_Explain(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMM(nil)
+	err := mm.CheckString(`$c |- wff ( ) -> $.
+$v ph ps $.
+wph $f wff ph $.
+wps $f wff ps $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  mp $a |- ps $.
+$}
+${
+  h1 $e |- ph $.
+  h2 $e |- ( ps -> ph ) $.
+  bad $p |- ps $= wph wps h1 h2 mp $.
+$}
+`)
+	failure := AsProofFailure(err)
+	if failure == nil {
+		t.Fatalf("expected a ProofFailure, got %v", err)
+	}
+	if failure.Step != 5 || failure.Label != "mp" {
+		t.Errorf("failed at step %d (%s), want step 5 (mp)", failure.Step, failure.Label)
+	}
+	explanation := failure.Explain()
+	for _, want := range []string{
+		"step 5 (mp)",
+		"  3: |- ( ps -> ph )",
+		"  ph := ph",
+		"expected: |- ( ph -> ps )",
+		"actual:   |- ( ps -> ph )",
+	} {
+		if !strings.Contains(explanation, want) {
+			t.Errorf("explanation does not contain %q:\n%s", want, explanation)
+		}
+	}
+	if AsMMError(err) == nil {
+		t.Error("ProofFailure should unwrap to MMError")
+	}
+}
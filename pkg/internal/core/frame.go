@@ -1,11 +1,11 @@
 package core
 
 type Frame struct {
-	V       map[string]TUnit
-	D       map[Dv]TUnit
-	F       []Fhyp
+	V map[string]TUnit
+	D map[Dv]TUnit
+	// The $f and $e hypotheses, in order.
+	Hyps    []Hyp
 	FLabels map[string]Label
-	E       []Ehyp
 	ELabels map[Symbols]Label
 	// Only for testing
 	Name string
@@ -16,9 +16,8 @@ func NewFrame() *Frame {
 	return &Frame{
 		V:       map[string]TUnit{},
 		D:       map[Dv]TUnit{},
-		F:       nil,
+		Hyps:    nil,
 		FLabels: map[string]Label{},
-		E:       nil,
 		ELabels: map[Symbols]Label{},
 	}
 }
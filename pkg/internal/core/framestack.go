@@ -52,14 +52,14 @@ func (self *FrameStack) LastFrame() *Frame {
 // Can this fail?
 func (self *FrameStack) AddE(stmt Stmt, label Label) {
 	frame := self.LastFrame()
-	frame.E = append(frame.E, Ehyp(stmt))
+	frame.Hyps = append(frame.Hyps, Hyp{Label: label, E: Ehyp(stmt)})
 	// Go doesn't have tuples (or another hashable connection)
 	// So we convert to a string, painfully.
 	// I wonder how slow this will be in benchmarks.
 	frame.ELabels[ToSymbols(stmt)] = label
 }
 
-// Add all distinct pairs
+// Add all pairs of distinct variables
 //
 // This is quadratic time for, like, no reason.
 //
@@ -70,6 +70,9 @@ func (self *FrameStack) AddD(varlist []string) {
 	frame := self.LastFrame()
 	for _, x := range varlist {
 		for _, y := range varlist {
+			if x == y {
+				continue
+			}
 			min := x
 			max := y
 			if string(y) < string(x) {
@@ -150,7 +153,7 @@ func (self *FrameStack) LookupE(stmt Stmt) (*Label, error) {
 		}
 		return GO
 	})
-	if out != nil {
+	if out == nil {
 		err = fmt.Errorf("lookup e failed: %v", stmt)
 	}
 	return out, err
@@ -166,53 +169,73 @@ func (self *FrameStack) FindVars(stmt Stmt) map[string]TUnit {
 	return out
 }
 
+// ActiveHypotheses returns the labels of the $f and $e hypotheses in scope.
+func (self *FrameStack) ActiveHypotheses() map[Label]TUnit {
+	out := map[Label]TUnit{}
+	for _, frame := range self.Frames {
+		for _, hyp := range frame.Hyps {
+			out[hyp.Label] = Unit
+		}
+	}
+	return out
+}
+
 func (self *FrameStack) MakeAssertion(stmt Stmt) Assertion {
-	var eHyps []Ehyp
 	mandVars := map[string]TUnit{}
 	dvs := map[Dv]TUnit{}
-	var fHyps []Fhyp
-
-	self.Foreach(func(frame *Frame) int8 {
-		for _, eh := range frame.E {
-			eHyps = append(eHyps, eh)
+	var hyps []Hyp
+
+	// The mandatory variables are those of the $e hypotheses and of the
+	// statement itself.
+	for _, frame := range self.Frames {
+		for _, hyp := range frame.Hyps {
+			if hyp.F != nil {
+				continue
+			}
+			for _, tok := range hyp.E {
+				if self.LookupV(tok) {
+					mandVars[tok] = Unit
+				}
+			}
 		}
-		return GO
-	})
+	}
+	for _, tok := range stmt {
+		if self.LookupV(tok) {
+			mandVars[tok] = Unit
+		}
+	}
 
-	// Do the weird thing for "efficiency".
-	// Add our statement to eHyps and then remove it.
-	// replaces itertools.chain(e_hyps, [stmt])
-	eHyps = append(eHyps, Ehyp(stmt))
-	for _, hyp := range eHyps {
-		for _, tok := range hyp {
-			if self.LookupV(tok) {
-				mandVars[tok] = Unit
+	// The mandatory $d pairs are the active ones between mandatory variables.
+	for _, frame := range self.Frames {
+		for dv := range frame.D {
+			_, first := mandVars[dv.First]
+			_, second := mandVars[dv.Second]
+			if first && second {
+				dvs[dv] = Unit
 			}
 		}
 	}
-	eHyps = eHyps[:-1+len(eHyps)]
 
-	self.Foreach(func(frame *Frame) int8 {
-		for _, p := range frame.F {
-			typecode := p.Typecode
-			va := p.V
-			if _, ok := mandVars[va]; ok {
-				fHyps = append(fHyps, Fhyp{
-					Typecode: typecode,
-					V:        va,
-				})
-				delete(mandVars, va)
+	// Hypotheses are ordered from the outermost frame inwards, unlike
+	// lookups, which search from the innermost frame outwards, and $f and
+	// $e hypotheses keep their relative order.
+	for _, frame := range self.Frames {
+		for _, hyp := range frame.Hyps {
+			if hyp.F == nil {
+				hyps = append(hyps, hyp)
+				continue
+			}
+			if _, ok := mandVars[hyp.F.V]; ok {
+				hyps = append(hyps, hyp)
+				delete(mandVars, hyp.F.V)
 			}
 		}
-		return GO
-	})
+	}
 
 	out := Assertion{
-		Dvs: dvs,
-		F:   fHyps,
-		E:   eHyps,
-		S:   stmt,
+		Dvs:  dvs,
+		Hyps: hyps,
+		S:    stmt,
 	}
-	Vprint(18, "Make assertion:", out.String())
 	return out
 }
@@ -1,6 +1,9 @@
 package core
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestFrameStack(t *testing.T) {
 	t.Parallel()
@@ -13,3 +16,64 @@ func TestFrameStack(t *testing.T) {
 		t.Error("framestack push failed")
 	}
 }
+
+func TestFrameStack_AddD(t *testing.T) {
+	t.Parallel()
+
+	framestack := NewFrameStack()
+	framestack.Push()
+	framestack.AddD([]string{"y", "x", "x"})
+
+	want := map[Dv]TUnit{{First: "x", Second: "y"}: Unit}
+	if !reflect.DeepEqual(framestack.Frames[0].D, want) {
+		t.Errorf("got %v, want %v", framestack.Frames[0].D, want)
+	}
+}
+
+func TestFrameStack_MakeAssertionDvs(t *testing.T) {
+	t.Parallel()
+
+	framestack := NewFrameStack()
+	framestack.Push()
+	for _, va := range []string{"x", "y", "z"} {
+		framestack.Frames[0].V[va] = Unit
+	}
+	framestack.AddD([]string{"x", "y", "z"})
+	framestack.Push()
+	framestack.AddE(Stmt{"|-", "y"}, "h")
+
+	// z is not mandatory, so neither are its pairs.
+	assertion := framestack.MakeAssertion(Stmt{"|-", "x"})
+	want := map[Dv]struct{}{{First: "x", Second: "y"}: {}}
+	if !reflect.DeepEqual(assertion.Dvs, want) {
+		t.Errorf("got %v, want %v", assertion.Dvs, want)
+	}
+}
+
+func TestFrameStack_MakeAssertionOrder(t *testing.T) {
+	t.Parallel()
+
+	framestack := NewFrameStack()
+	framestack.Push()
+	for _, va := range []string{"x", "y", "z"} {
+		framestack.Frames[0].V[va] = Unit
+	}
+	framestack.Frames[0].FLabels["x"] = "vx"
+	framestack.Frames[0].Hyps = append(framestack.Frames[0].Hyps, Hyp{Label: "vx", F: &Fhyp{Typecode: "set", V: "x"}})
+	framestack.AddE(Stmt{"|-", "x"}, "h")
+	framestack.Frames[0].FLabels["y"] = "vy"
+	framestack.Frames[0].Hyps = append(framestack.Frames[0].Hyps, Hyp{Label: "vy", F: &Fhyp{Typecode: "set", V: "y"}})
+	framestack.Frames[0].FLabels["z"] = "vz"
+	framestack.Frames[0].Hyps = append(framestack.Frames[0].Hyps, Hyp{Label: "vz", F: &Fhyp{Typecode: "set", V: "z"}})
+
+	// A $f hypothesis after a $e one keeps its place, and z is not mandatory.
+	assertion := framestack.MakeAssertion(Stmt{"|-", "y"})
+	var got []Label
+	for _, hyp := range assertion.Hyps {
+		got = append(got, hyp.Label)
+	}
+	want := []Label{"vx", "h", "vy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
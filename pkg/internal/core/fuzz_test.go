@@ -0,0 +1,142 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// The fuzz targets only check that every input ends in an error or a success.
+// Run one with, for example:
+//
+//	go test -fuzz=FuzzCheckString -fuzztime=1m ./pkg/internal/core
+
+const fuzzDatabase = `$( A small propositional calculus. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+ax-1 $a |- ( ph -> ( ps -> ph ) ) $.
+${
+  h $e |- ( ph -> ps ) $.
+  th $p |- ( ph -> ( ph -> ps ) ) $= ( wi ax-1 ax-mp ) ABDZAGDCGAEF $.
+$}
+`
+
+// fuzzLimits keep substitutions, which can double in size at every step of a
+// compressed proof, from taking all the fuzzer's time.
+var fuzzLimits = Limits{
+	MaxTokens:             10_000,
+	MaxStackDepth:         1_000,
+	MaxSubstitutionLength: 1_000,
+}
+
+// fuzzSeeds are small databases, some of them broken on purpose.
+var fuzzSeeds = []string{
+	fuzzDatabase,
+	limitsDatabase,
+	recoverDatabase,
+	selectionDatabase,
+	suggestDatabase,
+	visitorDatabase,
+	"",
+	"$( not closed",
+	"$c a $. $v a $.",
+	"$v a $. $c a $.",
+	"${ $c a $.",
+	"$c wff $. $v x $. wx $f wff x $. p $p wff x $= ( ) Z $.",
+	"$c wff $. $v x $. wx $f wff x $. p $p wff x $= ( wx ) 0A $.",
+	"$c wff $. $v x $. wx $f wff x $. p $p wff x $= ( wx $.",
+	"$[ other.mm $]",
+	"$c wff $. $v x $. wx $f wff x $. p $p wff x $= ( wx ) UUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUA $.",
+}
+
+// fuzzMM reads fuzzDatabase and then opens a scope in which the hypothesis h
+// is active again, as it would be inside the proof of th.
+func fuzzMM(t *testing.T) *MM {
+	t.Helper()
+
+	mm := NewMMWithOptions(Options{Limits: fuzzLimits})
+	if err := mm.CheckString(fuzzDatabase); err != nil {
+		t.Fatal(err)
+	}
+	mm.FS.Push()
+	mm.FS.AddE(Stmt{"|-", "(", "ph", "->", "ps", ")"}, "h")
+	return mm
+}
+
+func FuzzToks(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, content string) {
+		toks := NewStringToks(content)
+		toks.DisableIncludes = true
+		for {
+			tok, err := toks.Readc()
+			if err != nil || tok == "" {
+				return
+			}
+		}
+	})
+}
+
+func FuzzTreatCompressedProof(f *testing.F) {
+	f.Add("wi ax-1 ax-mp", "ABDZAGDCGAEF")
+	f.Add("", "")
+	f.Add("", "Z")
+	f.Add("wi", "UAZB")
+	f.Add("h h h", "YYYYZ")
+	f.Add("nope", "C")
+	f.Add("wph", "a0")
+	f.Add("ax-1", "UUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUA")
+	f.Fuzz(func(t *testing.T, labels string, steps string) {
+		mm := fuzzMM(t)
+		assertion := mm.FS.MakeAssertion(Stmt{"|-", "ps"})
+		proof := append([]string{"("}, strings.Fields(labels)...)
+		proof = append(proof, ")")
+		proof = append(proof, strings.Fields(steps)...)
+		stack, err := TreatCompressedProof(mm, assertion.Hyps, proof)
+		if err == nil && stack == nil {
+			t.Error("no stack and no error")
+		}
+	})
+}
+
+func FuzzTreatNormalProof(f *testing.F) {
+	f.Add("wph wps wi")
+	f.Add("wph wps h ax-mp")
+	f.Add("wph wph wps wi ax-1 wph wps h ax-mp")
+	f.Add("")
+	f.Add("ax-mp")
+	f.Add("? wph ?")
+	f.Add("min")
+	f.Fuzz(func(t *testing.T, proof string) {
+		mm := fuzzMM(t)
+		stack, err := TreatNormalProof(mm, strings.Fields(proof))
+		if err == nil && stack == nil {
+			t.Error("no stack and no error")
+		}
+	})
+}
+
+func FuzzCheckString(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+	f.Fuzz(func(t *testing.T, content string, recover bool) {
+		mm := NewMMWithOptions(Options{
+			Limits:          fuzzLimits,
+			DisableIncludes: true,
+			Recover:         recover,
+		})
+		_ = mm.CheckString(content)
+	})
+}
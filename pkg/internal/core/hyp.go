@@ -0,0 +1,22 @@
+package core
+
+// Hyp is a $f or $e hypothesis. Frames and assertions keep both kinds in one
+// list, in the order they appear, which is the order the spec gives mandatory
+// hypotheses. F is nil for a $e hypothesis.
+type Hyp struct {
+	Label Label
+	F     *Fhyp
+	E     Ehyp
+}
+
+// Stmt returns the statement of the hypothesis.
+func (hyp Hyp) Stmt() Stmt {
+	if hyp.F != nil {
+		return Stmt{hyp.F.Typecode, hyp.F.V}
+	}
+	return Stmt(hyp.E)
+}
+
+func (hyp Hyp) String() string {
+	return string(hyp.Label) + ": " + hyp.Stmt().String()
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+const limitsDatabase = `$c wff ( ) -> $.
+$v ph ps $.
+wph $f wff ph $.
+wps $f wff ps $.
+wi $a wff ( ph -> ps ) $.
+long $a wff ( ph -> ( ph -> ( ph -> ph ) ) ) $.
+deep $p wff ( ( ph -> ph ) -> ph ) $= wph wph wi wph wi $.
+after $a wff ph $.
+`
+
+func TestLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		limits Limits
+		msg    string
+		// Whether reading carried on to the last statement.
+		recovered bool
+	}{
+		{"statement length", Limits{MaxStatementLength: 10}, "$a-statement has more than the limit of 10 symbols", true},
+		{"proof length", Limits{MaxProofLength: 3}, "$=-statement has more than the limit of 3 labels", true},
+		{"stack depth", Limits{MaxStackDepth: 1}, "proof stack is deeper than the limit of 1", true},
+		{"substitution length", Limits{MaxSubstitutionLength: 6}, "statement of 10 symbols, more than the limit of 6", true},
+		{"tokens", Limits{MaxTokens: 10}, "read more tokens than the limit of 10", false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mm := NewMMWithOptions(Options{Limits: tt.limits, Recover: true})
+			err := mm.CheckString(limitsDatabase)
+			if !errors.Is(err, mmerror.LimitExceeded) || !strings.Contains(err.Error(), tt.msg) {
+				t.Fatalf("expected a limit error containing %q, got %v", tt.msg, err)
+			}
+			var last Label
+			if len(mm.Results) > 0 {
+				last = mm.Results[len(mm.Results)-1].Label
+			}
+			if recovered := last == "after"; recovered != tt.recovered {
+				t.Errorf("read up to %q", last)
+			}
+			if AsVerifyError(err) != nil {
+				t.Errorf("a limit error is a verification failure: %v", err)
+			}
+		})
+	}
+}
+
+func TestLimits_IncludeDepth(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.mm": "$[ b.mm $]\n",
+		"b.mm": "$[ c.mm $]\n",
+		"c.mm": "$c wff $.\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for depth, want := range map[int]string{1: "limit of 1 nested files", 2: ""} {
+		toks, err := NewToks(filepath.Join(dir, "a.mm"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		toks.IncludePath = []string{dir}
+		mm := NewMMWithOptions(Options{Limits: Limits{MaxIncludeDepth: depth}})
+		err = mm.Read(toks)
+		if IsEOF(err) {
+			err = nil
+		}
+		if want == "" && err != nil {
+			t.Errorf("depth %d: %v", depth, err)
+		}
+		if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("depth %d: expected an error containing %q, got %v", depth, want, err)
+		}
+	}
+}
+
+func TestScanCloser_LongLine(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "long.mm")
+	content := "$( " + strings.Repeat("x", 100_000) + " $)\n$c wff $.\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	toks, err := NewToks(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mm := NewMM(nil)
+	if err := mm.Read(toks); err != nil && !IsEOF(err) {
+		t.Fatal(err)
+	}
+	if _, ok := mm.Constants["wff"]; !ok {
+		t.Error("the line after a 100KB line was not read")
+	}
+}
+
+func TestLimits_LineLength(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("ph ", 100_000)
+	path := filepath.Join(t.TempDir(), "long.mm")
+	content := "$c wff $.\n$v ph $.\n" +
+		"$( " + long + " $)\n" +
+		"wph $f wff ph $.\n" +
+		"one $a wff " + long + " $.\n" +
+		"two $a wff\n" + long + "\n$.\n" +
+		"after $a wff ph $.\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keepGoing := range []bool{false, true} {
+		toks, err := NewToks(path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mm := NewMMWithOptions(Options{Limits: Limits{MaxLineLength: 1000}, Recover: keepGoing})
+		err = mm.Read(toks)
+		if !errors.Is(err, mmerror.LimitExceeded) || !strings.Contains(err.Error(), "line is longer than the limit of 1000 bytes") {
+			t.Fatalf("keep going %v: expected a line limit error, got %v", keepGoing, err)
+		}
+		if m := AsMMError(err); m == nil || m.Pos.Line != 3 {
+			t.Errorf("keep going %v: error is not on line 3: %v", keepGoing, err)
+		}
+		if !keepGoing {
+			continue
+		}
+		if len(mm.Errors) != 3 {
+			t.Errorf("got %d errors, want one for each long line: %v", len(mm.Errors), err)
+		}
+		if last := mm.Results[len(mm.Results)-1].Label; last != "after" {
+			t.Errorf("read up to %q", last)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Levels below slog.LevelDebug for the very chatty parts of the verifier.
+const (
+	// LevelTrace covers individual statements, proof steps and substitutions.
+	LevelTrace = slog.LevelDebug - 4
+	// LevelTokens covers every token read.
+	LevelTokens = slog.LevelDebug - 8
+)
+
+// Subsystem names. Every log record carries one as its "subsystem"
+// attribute, and they are the keys of Options.LogLevels.
+const (
+	SubsystemReader = "reader"
+	SubsystemToks   = "toks"
+	SubsystemProof  = "proof"
+)
+
+// subsystemLogger derives the logger for one subsystem. A level in levels
+// overrides whatever level the base handler would apply, so tracing can be
+// turned on for one subsystem only.
+func subsystemLogger(base *slog.Logger, levels map[string]slog.Level, name string) *slog.Logger {
+	if base == nil {
+		return slog.New(discardHandler{})
+	}
+	logger := base
+	if level, ok := levels[name]; ok {
+		logger = slog.New(levelHandler{level: level, inner: base.Handler()})
+	}
+	return logger.With(slog.String("subsystem", name))
+}
+
+// logCtx is the context passed to the logger.
+func (self *MM) logCtx() context.Context {
+	if self.ctx != nil {
+		return self.ctx
+	}
+	return context.Background()
+}
+
+// levelHandler replaces the minimum level of the handler it wraps.
+type levelHandler struct {
+	level slog.Leveler
+	inner slog.Handler
+}
+
+func (h levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.inner.Handle(ctx, record)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{level: h.level, inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{level: h.level, inner: h.inner.WithGroup(name)}
+}
+
+// discardHandler drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// tracing reports whether logger records LevelTrace messages, so that hot
+// paths can skip building attributes.
+func (self *MM) tracing(logger *slog.Logger) bool {
+	return logger.Enabled(self.logCtx(), LevelTrace)
+}
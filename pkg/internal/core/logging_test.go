@@ -0,0 +1,54 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+// TestLogLevels checks that token tracing can be enabled for one subsystem
+// without enabling it for the others.
+func TestLogLevels(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	mm := NewMMWithOptions(Options{
+		Logger:    logger,
+		LogLevels: map[string]slog.Level{SubsystemToks: LevelTokens},
+	})
+	if err := mm.CheckString(visitorDatabase); err != nil {
+		t.Fatal(err)
+	}
+
+	subsystems := map[string]int{}
+	sawToken := false
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("bad JSON log line %q: %v", line, err)
+		}
+		subsystem, _ := record["subsystem"].(string)
+		subsystems[subsystem]++
+		if record["msg"] == "token" && record["token"] == "$c" {
+			sawToken = true
+		}
+	}
+
+	if !sawToken {
+		t.Error("expected token-level records from the toks subsystem")
+	}
+	if subsystems[SubsystemProof] != 0 || subsystems[SubsystemReader] != 0 {
+		t.Errorf("unexpected records from other subsystems: %v", subsystems)
+	}
+}
+
+func TestLogLevels_NilLogger(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMMWithOptions(Options{LogLevels: map[string]slog.Level{SubsystemProof: LevelTrace}})
+	if err := mm.CheckString(visitorDatabase); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,50 +1,150 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
-	"strings"
+	"time"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
 )
 
 type MM struct {
-	BeginLabel   *Label
-	EndLabel     *Label
-	Constants    map[string]struct{}
+	BeginLabel *Label
+	EndLabel   *Label
+	Constants  map[string]struct{}
+	// Every variable declared so far, active or not, and the typecode its
+	// first $f statement gave it.
+	Variables map[string]struct{}
+	typecodes map[string]string
+	// Constants and variables in the order they were declared. A variable
+	// declared in several scopes appears once per declaration.
+	ConstantList []string
+	VariableList []string
 	FS           *FrameStack
 	Labels       map[Label]*FullStmt
 	VerifyProofs bool
+	// One entry per labeled statement, in the order they were read.
+	Results []Result
+	// Optional. Receives a callback for each statement as it is read.
+	Visitor Visitor
+	Limits  Limits
+	// If positive, each proof must be verified within this time.
+	ProofTimeout time.Duration
+	// Optional. Called after each statement, but no more often than
+	// ProgressInterval, and once more when reading stops.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+	// Recover keeps reading after a statement fails, collecting every
+	// diagnostic in Errors, until MaxErrors of them if that is positive.
+	Recover   bool
+	MaxErrors int
+	Errors    []error
+	// AllowIncomplete records a proof with unknown steps in Results without
+	// failing the statement.
+	AllowIncomplete bool
+	// parseOnly keeps BeginLabel from turning proof checking on.
+	parseOnly bool
+	// Decides which proofs are checked; nil checks them all.
+	selector   *selector
+	noIncludes bool
+	// One logger per subsystem.
+	log      *slog.Logger
+	toksLog  *slog.Logger
+	proofLog *slog.Logger
+	// Set for the duration of ReadContext and of a single proof.
+	ctx      context.Context
+	proofCtx context.Context
+	// The most recently read label, for error messages, and where each
+	// label read so far starts.
+	current  Label
+	labelPos map[Label]Pos
+	// Whether current is in the Selection.
+	selected bool
+	// Counters for Progress.
+	statements     int
+	proofsVerified int
+	lastProgress   time.Time
+	// Set once EndLabel has been reached. Nested calls to Read unwind
+	// without reading further.
+	stopped bool
 }
 
 func NewMM(beginLabel *Label) *MM {
-	return &MM{
-		BeginLabel:   beginLabel,
-		EndLabel:     nil,
-		Constants:    map[string]TUnit{},
-		Labels:       map[Label]*FullStmt{},
-		VerifyProofs: beginLabel == nil,
-		FS:           NewFrameStack(),
-	}
+	return NewMMWithOptions(Options{BeginLabel: beginLabel})
 }
 
 func (self *MM) AddC(tok string) error {
+	if err := checkMathSymbol(tok); err != nil {
+		return err
+	}
+	if len(self.FS.Frames) > 1 {
+		return MMError{Code: mmerror.UnexpectedToken, err: fmt.Errorf("constant %q declared in an inner block, constants are declared in the outermost one", tok)}
+	}
 	_, ok := self.Constants[tok]
 	if ok {
-		return MMError{fmt.Errorf("constant %q already declared", tok)}
+		return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("constant %q already declared", tok)}
+	}
+	// Unlike a variable, a constant stays active to the end of the
+	// database, so it may not reuse the name of a variable whose scope has
+	// ended either.
+	if _, ok := self.Variables[tok]; ok {
+		return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("constant %q already declared as a variable", tok)}
+	}
+	if _, ok := self.Labels[Label(tok)]; ok {
+		return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("constant %q already used as a label", tok)}
 	}
 	self.Constants[tok] = struct{}{}
+	self.ConstantList = append(self.ConstantList, tok)
 	return nil
 }
 
 func (self *MM) AddV(tok string) error {
+	if err := checkMathSymbol(tok); err != nil {
+		return err
+	}
 	if self.FS.LookupV(tok) {
-		return MMError{fmt.Errorf("variable %q already declared and active", tok)}
+		return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("variable %q already declared and active", tok)}
+	}
+	if _, ok := self.Constants[tok]; ok {
+		return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("variable %q already declared as a constant", tok)}
+	}
+	if _, ok := self.Labels[Label(tok)]; ok {
+		return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("variable %q already used as a label", tok)}
 	}
 	frame := self.FS.LastFrame()
 	if frame == nil {
 		panic("impossible: frame stack is empty")
 	}
 	frame.V[tok] = struct{}{}
+	self.Variables[tok] = struct{}{}
+	self.VariableList = append(self.VariableList, tok)
+	return nil
+}
+
+// checkMathSymbol checks that a math symbol being declared is made of
+// printable ASCII characters other than $.
+func checkMathSymbol(tok string) error {
+	for _, r := range tok {
+		if r < '!' || r > '~' || r == '$' {
+			return MMError{Code: mmerror.UnexpectedToken, err: fmt.Errorf("math symbol %q may not contain %q", tok, r)}
+		}
+	}
+	return nil
+}
+
+// checkLabel checks that a label is made of letters, digits, hyphens,
+// underscores and periods.
+func checkLabel(tok string) error {
+	for _, r := range tok {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return MMError{Code: mmerror.UnexpectedToken, err: fmt.Errorf("label %q may not contain %q", tok, r)}
+		}
+	}
 	return nil
 }
 
@@ -52,12 +152,12 @@ func (self *MM) AddF(typecode string, va string, label Label) error {
 	if self.FS.LookupV(va) {
 		// Good. We need the variable to already exist.
 	} else {
-		return MMError{fmt.Errorf("var in $f not declared: %q", va)}
+		return self.unknownVariable(va)
 	}
 	if _, ok := self.Constants[typecode]; ok {
 		// Good. The constant must exist already.
 	} else {
-		return MMError{fmt.Errorf("typecode in $f not declared: %q", typecode)}
+		return self.unknownConstant(typecode)
 	}
 
 	alreadyTyped := false
@@ -70,27 +170,34 @@ func (self *MM) AddF(typecode string, va string, label Label) error {
 		return GO
 	})
 	if alreadyTyped {
-		return MMError{fmt.Errorf("var in $f already typed by an active $f-statement: %q", va)}
+		return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("var in $f already typed by an active $f-statement: %q", va)}
+	}
+	if earlier, ok := self.typecodes[va]; ok && earlier != typecode {
+		return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("var in $f already typed %q by an earlier $f-statement: %q", earlier, va)}
 	}
+	self.typecodes[va] = typecode
 	frame := self.FS.LastFrame()
 	if frame == nil {
 		panic("impossible")
 	}
-	frame.F = append(frame.F, Fhyp{
-		Typecode: typecode,
-		V:        va,
+	frame.Hyps = append(frame.Hyps, Hyp{
+		Label: label,
+		F: &Fhyp{
+			Typecode: typecode,
+			V:        va,
+		},
 	})
 	frame.FLabels[va] = label
 	return nil
 }
 
 // *Symbol, *Var, *Const
+//
+// AddC and AddV keep a symbol from being both a variable and a constant.
 func (self *MM) LookupSymbolByName(tok string) (*string, *string, *string) {
 	isActiveVar := self.FS.LookupV(tok)
 	_, isConstant := self.Constants[tok]
 	switch {
-	case isActiveVar && isConstant:
-		panic(fmt.Sprintf("string %q is both var and const", tok))
 	case isActiveVar:
 		return &tok, &tok, nil
 	case isConstant:
@@ -100,45 +207,73 @@ func (self *MM) LookupSymbolByName(tok string) (*string, *string, *string) {
 	}
 }
 
+// checkD checks that a $d statement names distinct active variables.
+func (self *MM) checkD(stmt Stmt) error {
+	seen := map[string]TUnit{}
+	for _, tok := range stmt {
+		if !self.FS.LookupV(tok) {
+			return MMError{Code: mmerror.MalformedStatement, err: fmt.Errorf("$d statement names %q, which is not an active variable", tok)}
+		}
+		if _, ok := seen[tok]; ok {
+			return MMError{Code: mmerror.MalformedStatement, err: fmt.Errorf("$d statement names %q twice", tok)}
+		}
+		seen[tok] = Unit
+	}
+	return nil
+}
+
 // endToken is "$=" or "$.".
 // endToken shouldn't be a string this function is too general.
 func (self *MM) ReadStmtAux(stmttype string, toks *Toks, endToken string) (Stmt, error) {
 	Assert(endToken == "$=" || endToken == "$.", `endToken is $. or $=`)
 	var stmt Stmt
-	tok, err := toks.Readc()
-	if err != nil {
-		return nil, fmt.Errorf("failed to readc: %w", err)
+	limit, what := self.Limits.MaxStatementLength, "symbols"
+	if stmttype == "$=" {
+		limit, what = self.Limits.MaxProofLength, "labels"
 	}
-	for tok != "" && tok != endToken {
-		// What do we do if the symbol doesn't exist?
-		sym, va, constant := self.LookupSymbolByName(tok)
+	tok, err := toks.Readc()
+	for err == nil && tok != "" && tok != endToken {
+		if limit > 0 && len(stmt) >= limit {
+			return nil, MMError{Code: mmerror.LimitExceeded, err: fmt.Errorf("%s-statement has more than the limit of %d %s", stmttype, limit, what), Pos: toks.Pos()}
+		}
+		// Proofs are made of labels and $c, $v introduce new symbols,
+		// so only the remaining statement types are checked here.
+		_, va, constant := self.LookupSymbolByName(tok)
 		// Validate active symbol.
 		switch stmttype {
 		case "$d", "$e", "$a", "$p":
 			if va == nil && constant == nil {
-				return nil, MMError{fmt.Errorf("Token %q is not an active symbol", tok)}
+				return nil, self.unknownSymbol(tok, toks.Pos())
 			}
 		}
 		// Validate symbol typed by hypothesis.
 		switch stmttype {
 		case "$e", "$a", "$p":
 			if va != nil && self.FS.LookupF(*va) == nil {
-				return nil, MMError{fmt.Errorf("Variable %q in %s-statement is not typed by an active $f-statement", tok, stmttype)}
+				return nil, MMError{Code: mmerror.UntypedVariable, err: fmt.Errorf("Variable %q in %s-statement is not typed by an active $f-statement", tok, stmttype), Pos: toks.Pos()}
 			}
 		}
-		stmt = append(stmt, *sym)
+		stmt = append(stmt, tok)
 		tok, err = toks.Readc()
-		if err != nil {
-			return nil, fmt.Errorf("failed to readc in processing loop: %w", err)
-		}
 	}
-	if tok == "" {
-		return nil, MMError{fmt.Errorf("Unclosed %q-statement at the end of file", stmttype)}
+	if IsEOF(err) || tok == "" {
+		return nil, MMError{Code: mmerror.UnclosedStatement, err: fmt.Errorf("Unclosed %q-statement at the end of file", stmttype)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to readc: %w", err)
 	}
 	if tok != endToken {
 		panic("tok must equal endToken")
 	}
-	Vprint(20, "Statement:", stmt.String())
+	switch stmttype {
+	case "$e", "$a", "$p":
+		if len(stmt) == 0 {
+			return nil, MMError{Code: mmerror.MalformedStatement, err: fmt.Errorf("%s-statement must start with a typecode", stmttype), Pos: toks.Pos()}
+		}
+	}
+	if self.tracing(self.log) {
+		self.log.LogAttrs(self.logCtx(), LevelTrace, "statement", slog.String("type", stmttype), slog.Any("stmt", stmt))
+	}
 	return stmt, nil
 }
 
@@ -159,150 +294,403 @@ func (self *MM) ReadPStatement(toks *Toks) (Stmt, Stmt, error) {
 }
 
 func (self *MM) Read(toks *Toks) error {
+	return self.ReadContext(context.Background(), toks)
+}
+
+// ReadContext is like Read but stops with a CancelError once ctx is done.
+// The context is checked before every statement and every proof step.
+func (self *MM) ReadContext(ctx context.Context, toks *Toks) error {
+	self.ctx = ctx
+	defer func() { self.ctx = nil }()
+	if self.Visitor != nil {
+		toks.OnComment = func(text []string) error {
+			return self.visit(func(v Visitor) error { return v.OnComment(text) })
+		}
+		toks.OnInclude = func(path string) error {
+			return self.visit(func(v Visitor) error { return v.OnInclude(path) })
+		}
+	}
+	toks.Log = self.toksLog
+	toks.MaxIncludes = self.Limits.MaxIncludes
+	toks.MaxIncludeDepth = self.Limits.MaxIncludeDepth
+	toks.MaxTokens = self.Limits.MaxTokens
+	toks.MaxLineLength = self.Limits.MaxLineLength
+	toks.DisableIncludes = self.noIncludes
+	err := self.read(toks)
+	err = self.explainLater(err, toks)
+	self.reportProgress(toks, true)
+	if errors.Is(err, ErrStop) {
+		self.stopped = true
+		err = nil
+	}
+	if len(self.Errors) == 0 {
+		return err
+	}
+	if err != nil && !IsEOF(err) {
+		self.Errors = append(self.Errors, err)
+	}
+	return errors.Join(self.Errors...)
+}
+
+// blockState is what read remembers between the statements of one block.
+type blockState struct {
+	// The label waiting for its statement, and where it starts.
+	label    *Label
+	labelPos Pos
+}
+
+func (self *MM) read(toks *Toks) error {
 	self.FS.Push()
-	var label *Label
-	tok, err := toks.Readc()
+	var st blockState
+	tok, err := self.readc(toks)
 	if err != nil {
 		return fmt.Errorf("readc: %w", err)
 	}
-	for tok != "" && tok != "$}" {
-		switch tok {
-		case "$c":
-			stmt, err := self.ReadNonPStatement(tok, toks)
-			if err != nil {
-				return fmt.Errorf("read non-p statement: %w", err)
-			}
-			for _, w := range stmt {
-				if err := self.AddC(w); err != nil {
-					return fmt.Errorf("addc: %w", err)
-				}
+	// A $} in the outermost block has no ${ to close, so it is left to
+	// readStatement to reject.
+	for tok != "" && (tok != "$}" || len(self.FS.Frames) == 1) {
+		if err := self.checkCtx(); err != nil {
+			return err
+		}
+		stmtPos := toks.Pos()
+		if err := self.readStatement(tok, toks, &st); err != nil {
+			err = locate(err, stmtPos, st.label, st.labelPos)
+			if !self.Recover || !recoverable(err) {
+				return err
 			}
-		case "$v":
-			stmt, err := self.ReadNonPStatement(tok, toks)
-			if err != nil {
-				return fmt.Errorf("read non-p statement in $v: %w", err)
+			if err := self.recordError(err); err != nil {
+				return err
 			}
-			for _, w := range stmt {
-				if err := self.AddV(w); err != nil {
-					return fmt.Errorf("add variable $v: %w", err)
-				}
+			if self.stopped {
+				return nil
 			}
-		case "$f":
-			stmt, err := self.ReadNonPStatement(tok, toks)
+			st.label = nil
+			tok, err = self.resync(toks)
 			if err != nil {
-				return MMError{fmt.Errorf("read statement in $f: %w", err)}
+				return fmt.Errorf("resync: %w", err)
 			}
-			if label == nil {
-				return MMError{fmt.Errorf("$f must have label (statement: %s)", stmt.String())}
-			}
-			if len(stmt) != 2 {
-				return MMError{fmt.Errorf("$f must have length 2 but is %v", stmt.String())}
+			continue
+		}
+		if self.stopped {
+			return nil
+		}
+		if tok[0] == '$' {
+			self.statements++
+			self.reportProgress(toks, false)
+		}
+		tok, err = self.readc(toks)
+		if err != nil {
+			return fmt.Errorf("reading tok: %w", err)
+		}
+	}
+	self.FS.Pop()
+	return nil
+}
+
+// readStatement reads the statement or label starting with tok.
+func (self *MM) readStatement(tok string, toks *Toks, st *blockState) error {
+	switch tok {
+	case "$c":
+		stmt, err := self.ReadNonPStatement(tok, toks)
+		if err != nil {
+			return fmt.Errorf("read non-p statement: %w", err)
+		}
+		for _, w := range stmt {
+			if err := self.AddC(w); err != nil {
+				return fmt.Errorf("addc: %w", err)
 			}
-			if err := self.AddF(stmt[0], stmt[1], *label); err != nil {
-				return MMError{fmt.Errorf("$f: %w", err)}
+			if err := self.visit(func(v Visitor) error { return v.OnConstant(w) }); err != nil {
+				return fmt.Errorf("visitor: %w", err)
 			}
-			self.Labels[*label] = (&FullStmt{
-				SType: "$f",
-				MStmt: &stmt,
-			}).Check()
-			label = nil
-		case "$e":
-			if label == nil {
-				return MMError{errors.New("$e must have label")}
+		}
+	case "$v":
+		stmt, err := self.ReadNonPStatement(tok, toks)
+		if err != nil {
+			return fmt.Errorf("read non-p statement in $v: %w", err)
+		}
+		for _, w := range stmt {
+			if err := self.AddV(w); err != nil {
+				return fmt.Errorf("add variable $v: %w", err)
 			}
-			stmt, err := self.ReadNonPStatement(tok, toks)
-			if err != nil {
-				return MMError{fmt.Errorf("$e failed to read: %w", err)}
+			if err := self.visit(func(v Visitor) error { return v.OnVariable(w) }); err != nil {
+				return fmt.Errorf("visitor: %w", err)
 			}
-			self.FS.AddE(stmt, *label)
-			self.Labels[*label] = (&FullStmt{
-				SType: "$e",
-				MStmt: &stmt,
-			}).Check()
-			label = nil
-		case "$a":
-			if label == nil {
-				return MMError{errors.New("$a must have label")}
+		}
+	case "$f":
+		stmt, err := self.ReadNonPStatement(tok, toks)
+		if err != nil {
+			return MMError{err: fmt.Errorf("read statement in $f: %w", err)}
+		}
+		if st.label == nil {
+			return MMError{Code: mmerror.MissingLabel, err: fmt.Errorf("$f must have label (statement: %s)", stmt.String())}
+		}
+		if len(stmt) != 2 {
+			return MMError{Code: mmerror.MalformedStatement, err: fmt.Errorf("$f must have length 2 but is %v", stmt.String())}
+		}
+		if err := self.AddF(stmt[0], stmt[1], *st.label); err != nil {
+			return MMError{err: fmt.Errorf("$f: %w", err)}
+		}
+		self.Labels[*st.label] = (&FullStmt{
+			SType: "$f",
+			MStmt: &stmt,
+		}).Check()
+		self.addResult(*st.label, "$f", st.labelPos, false, nil)
+		if err := self.visit(func(v Visitor) error { return v.OnHypothesis(*st.label, "$f", stmt) }); err != nil {
+			return fmt.Errorf("visitor: %w", err)
+		}
+		st.label = nil
+	case "$e":
+		if st.label == nil {
+			return MMError{Code: mmerror.MissingLabel, err: errors.New("$e must have label")}
+		}
+		stmt, err := self.ReadNonPStatement(tok, toks)
+		if err != nil {
+			return MMError{err: fmt.Errorf("$e failed to read: %w", err)}
+		}
+		self.FS.AddE(stmt, *st.label)
+		self.Labels[*st.label] = (&FullStmt{
+			SType: "$e",
+			MStmt: &stmt,
+		}).Check()
+		self.addResult(*st.label, "$e", st.labelPos, false, nil)
+		if err := self.visit(func(v Visitor) error { return v.OnHypothesis(*st.label, "$e", stmt) }); err != nil {
+			return fmt.Errorf("visitor: %w", err)
+		}
+		st.label = nil
+	case "$a":
+		if st.label == nil {
+			return MMError{Code: mmerror.MissingLabel, err: errors.New("$a must have label")}
+		}
+		stmt, err := self.ReadNonPStatement(tok, toks)
+		if err != nil {
+			return fmt.Errorf("reading statement in $a: %w", err)
+		}
+		assertion := self.FS.MakeAssertion(stmt)
+		if self.tracing(self.log) {
+			self.log.LogAttrs(self.logCtx(), LevelTrace, "make assertion", slog.String("label", string(*st.label)), slog.Any("assertion", &assertion))
+		}
+		self.Labels[*st.label] = (&FullStmt{
+			SType:      "$a",
+			MAssertion: &assertion,
+		})
+		self.addResult(*st.label, "$a", st.labelPos, false, nil)
+		if err := self.visit(func(v Visitor) error { return v.OnAxiom(*st.label, &assertion) }); err != nil {
+			return fmt.Errorf("visitor: %w", err)
+		}
+		st.label = nil
+	case "$p":
+		if st.label == nil {
+			return MMError{Code: mmerror.MissingLabel, err: errors.New("label cannot be new in $p statement")}
+		}
+		stmt, proof, err := self.ReadPStatement(toks)
+		if err != nil {
+			return fmt.Errorf("$p failed to read statement: %w", err)
+		}
+		assertion := self.FS.MakeAssertion(stmt)
+		if self.tracing(self.log) {
+			self.log.LogAttrs(self.logCtx(), LevelTrace, "make assertion", slog.String("label", string(*st.label)), slog.Any("assertion", &assertion))
+		}
+		if self.VerifyProofs && self.selected {
+			self.log.Log(
+				self.logCtx(),
+				slog.LevelDebug,
+				"verify",
+				slog.String("label", string(*st.label)),
+				slog.String("file", st.labelPos.File),
+				slog.Int("line", st.labelPos.Line),
+			)
+			self.proofsVerified++
+			if err := self.verifyWithDeadline(assertion, proof); err != nil {
+				if cancelErr := AsCancelError(err); cancelErr != nil {
+					return *cancelErr
+				}
+				// A proof that goes over a limit is neither valid nor
+				// invalid: it was not checked.
+				checked := !errors.Is(err, mmerror.LimitExceeded)
+				var verifyErr error = VerifyError{Label: *st.label, err: err}
+				if !checked {
+					verifyErr = err
+				}
+				// Register the theorem anyway, so that when recovering
+				// the proofs that use it can still be checked.
+				self.Labels[*st.label] = (&FullStmt{
+					SType:      "$p",
+					MAssertion: &assertion,
+				}).Check()
+				result := self.addResult(*st.label, "$p", st.labelPos, checked, verifyErr)
+				incomplete := errors.Is(err, mmerror.IncompleteProof)
+				if incomplete {
+					result.Missing = countUnknownSteps(proof)
+					self.Results[len(self.Results)-1] = result
+				}
+				err := self.visit(func(v Visitor) error { return v.OnTheorem(*st.label, &assertion, proof, result) })
+				if errors.Is(err, ErrStop) {
+					self.stopped = true
+				} else if err != nil {
+					return fmt.Errorf("visitor: %w", err)
+				}
+				if incomplete && self.AllowIncomplete {
+					st.label = nil
+					return nil
+				}
+				return verifyErr
 			}
-			stmt, err := self.ReadNonPStatement(tok, toks)
-			if err != nil {
-				return fmt.Errorf("reading statement in $a: %w", err)
+		}
+		self.Labels[*st.label] = (&FullStmt{
+			SType:      "$p",
+			MAssertion: &assertion,
+		}).Check()
+		result := self.addResult(*st.label, "$p", st.labelPos, self.VerifyProofs && self.selected, nil)
+		if err := self.visit(func(v Visitor) error { return v.OnTheorem(*st.label, &assertion, proof, result) }); err != nil {
+			return fmt.Errorf("visitor: %w", err)
+		}
+		st.label = nil
+	case "$d":
+		stmt, err := self.ReadNonPStatement(tok, toks)
+		if err != nil {
+			return fmt.Errorf("$d: %w", err)
+		}
+		if err := self.checkD(stmt); err != nil {
+			return fmt.Errorf("$d: %w", err)
+		}
+		self.FS.AddD(stmt)
+		if err := self.visit(func(v Visitor) error { return v.OnDisjoint(stmt) }); err != nil {
+			return fmt.Errorf("visitor: %w", err)
+		}
+	case "${":
+		if err := self.visit(func(v Visitor) error { return v.OnScopeOpen() }); err != nil {
+			return fmt.Errorf("visitor: %w", err)
+		}
+		if err := self.read(toks); err != nil {
+			if IsEOF(err) {
+				return MMError{Code: mmerror.UnclosedBlock, err: errors.New("Unclosed ${ ... $} block at end of file")}
 			}
-			assertion := self.FS.MakeAssertion(stmt)
-			self.Labels[*label] = (&FullStmt{
-				SType:      "$a",
-				MAssertion: &assertion,
-			})
-			label = nil
-		case "$p":
-			if label == nil {
-				return MMError{errors.New("label cannot be new in $p statement")}
+			// Already located, and left unwrapped so that explainLater
+			// can annotate it.
+			return err
+		}
+		if self.stopped {
+			return nil
+		}
+		if err := self.visit(func(v Visitor) error { return v.OnScopeClose() }); err != nil {
+			return fmt.Errorf("visitor: %w", err)
+		}
+	case "$)":
+		return MMError{Code: mmerror.UnexpectedToken, err: errors.New("Unexpected $) while not within a comment")}
+	case "$}":
+		return MMError{Code: mmerror.UnexpectedToken, err: errors.New("Unexpected $} outside of a ${ ... $} block")}
+	default:
+		if tok[0] != '$' {
+			if err := checkLabel(tok); err != nil {
+				return err
 			}
-			stmt, proof, err := self.ReadPStatement(toks)
-			if err != nil {
-				return fmt.Errorf("$p failed to read statement: %w", err)
+			_, ok := self.Labels[Label(tok)]
+			if ok {
+				return MMError{Code: mmerror.DuplicateLabel, err: fmt.Errorf("tok %q multiply defined", tok), Label: Label(tok), LabelPos: toks.Pos()}
 			}
-			assertion := self.FS.MakeAssertion(stmt)
-			if self.VerifyProofs {
-				Vprint(2, "Verify:", string(*label))
-				if err := self.Verify(assertion.F, assertion.E, assertion.S, proof); err != nil {
-					return fmt.Errorf("verification error: %w", err)
-				}
+			if _, ok := self.Constants[tok]; ok {
+				return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("label %q already declared as a constant", tok), Label: Label(tok), LabelPos: toks.Pos()}
 			}
-			self.Labels[*label] = (&FullStmt{
-				SType:      "$p",
-				MAssertion: &assertion,
-			}).Check()
-			label = nil
-		case "$d":
-			stmt, err := self.ReadNonPStatement(tok, toks)
-			if err != nil {
-				return fmt.Errorf("$d: %w", err)
+			if _, ok := self.Variables[tok]; ok {
+				return MMError{Code: mmerror.DuplicateSymbol, err: fmt.Errorf("label %q already declared as a variable", tok), Label: Label(tok), LabelPos: toks.Pos()}
 			}
-			self.FS.AddD(stmt)
-		case "${":
-			if err := self.Read(toks); err != nil {
-				return fmt.Errorf("${: %w", err)
+			l := Label(tok)
+			st.label = &l
+			st.labelPos = toks.Pos()
+			self.current = l
+			self.selected = self.selector.next(l)
+			self.log.Log(
+				self.logCtx(),
+				LevelTrace,
+				"label",
+				slog.String("label", tok),
+				slog.String("file", st.labelPos.File),
+				slog.Int("line", st.labelPos.Line),
+			)
+			if self.EndLabel != nil && *st.label == *self.EndLabel {
+				self.stopped = true
+				return nil
 			}
-		case "$)":
-			return errors.New("Unexpected $) while not within a comment")
-		default:
-			if tok[0] != '$' {
-				_, ok := self.Labels[Label(tok)]
-				if ok {
-					return fmt.Errorf("tok %q multiply defined", tok)
-				}
-				l := Label(tok)
-				label = &l
-				Vprint(20, "Label:", tok)
-				if self.EndLabel != nil && *label == *self.EndLabel {
-					// This is terrible. I need a better way to do this.
-					panic("SUCCESS")
-				}
-				if self.BeginLabel != nil && *label == *self.BeginLabel {
-					self.VerifyProofs = true
-				}
-			} else {
-				return fmt.Errorf("unknown token: %q", tok)
+			if self.BeginLabel != nil && *st.label == *self.BeginLabel && !self.parseOnly {
+				self.VerifyProofs = true
 			}
+		} else {
+			return MMError{Code: mmerror.UnexpectedToken, err: fmt.Errorf("unknown token: %q", tok)}
 		}
-		tok, err = toks.Readc()
+	}
+	return nil
+}
+
+// recordError keeps a diagnostic in recovery mode, and fails once
+// MaxErrors of them have been kept.
+func (self *MM) recordError(err error) error {
+	self.Errors = append(self.Errors, err)
+	self.log.Log(self.logCtx(), slog.LevelInfo, "recovering", slog.Any("error", err))
+	if self.MaxErrors > 0 && len(self.Errors) >= self.MaxErrors {
+		return MMError{Code: mmerror.TooManyErrors, err: fmt.Errorf("too many errors (%d)", len(self.Errors))}
+	}
+	return nil
+}
+
+// resync skips the rest of a statement that could not be read, up to and
+// including its $., and returns the token after it. It stops early at a
+// scope bracket so that blocks stay balanced. A stray $} is a statement of
+// its own, so reading resumes right after it.
+func (self *MM) resync(toks *Toks) (string, error) {
+	tok := toks.Last()
+	if tok == "$}" {
+		return self.readc(toks)
+	}
+	for tok != "$." {
+		var err error
+		tok, err = self.readc(toks)
 		if err != nil {
-			return fmt.Errorf("reading tok: %w", err)
+			return "", err
+		}
+		if tok == "" || tok == "${" || tok == "$}" {
+			return tok, nil
 		}
 	}
-	self.FS.Pop()
-	return nil
+	return self.readc(toks)
+}
+
+// readc reads the next token between statements. When recovering, a line
+// skipped for being over MaxLineLength is recorded and reading goes on with
+// the line after it.
+func (self *MM) readc(toks *Toks) (string, error) {
+	for {
+		tok, err := toks.Readc()
+		if err == nil || !self.Recover || !errors.Is(err, errLineLimit) {
+			return tok, err
+		}
+		if err := self.recordError(err); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (self *MM) addResult(label Label, stype string, pos Pos, checked bool, err error) Result {
+	self.labelPos[label] = pos
+	result := Result{
+		Label:    label,
+		SType:    stype,
+		Pos:      pos,
+		Checked:  checked,
+		Selected: stype == "$p" && self.selected,
+		Err:      err,
+	}
+	self.Results = append(self.Results, result)
+	return result
 }
 
-func (self *MM) Verify(fHyps []Fhyp, eHyps []Ehyp, conclusion Stmt, proof []string) error {
+func (self *MM) Verify(hyps []Hyp, conclusion Stmt, proof []string) error {
 	var stack *ProofStack = NewProofStack()
 	var err error = nil
 	if len(proof) == 0 {
-		return MMError{errors.New("proof is empty")}
+		return MMError{Code: mmerror.MalformedProof, err: errors.New("proof is empty")}
 	}
 	if proof[0] == "(" {
-		if stack, err = TreatCompressedProof(self, fHyps, eHyps, proof); err != nil {
+		if stack, err = TreatCompressedProof(self, hyps, proof); err != nil {
 			return fmt.Errorf("treating compressed proof: %w", err)
 		}
 	} else {
@@ -311,25 +699,33 @@ func (self *MM) Verify(fHyps []Fhyp, eHyps []Ehyp, conclusion Stmt, proof []stri
 		}
 	}
 	Assert(stack != nil, "Proof stack cannot be nil after this point")
-	Vprint(10, "Stack at end of proof:", fmt.Sprintf("%v", stack))
+	if self.tracing(self.proofLog) {
+		self.proofLog.LogAttrs(self.logCtx(), LevelTrace, "stack at end of proof", slog.Int("depth", len(stack.data)), slog.Any("stack", stack.data))
+	}
 	if len(stack.data) == 0 {
-		return MMError{errors.New("Empty stack at end of proof")}
+		return MMError{Code: mmerror.ConclusionMismatch, err: errors.New("Empty stack at end of proof")}
 	}
 	if len(stack.data) > 1 {
-		return MMError{fmt.Errorf(
+		return stack.fail(nil, mmerror.ConclusionMismatch, fmt.Errorf(
 			"Stack has more than one entry at the end of the proof (top entry %v) proved assertion %v",
 			stack.data[0],
 			conclusion,
-		)}
+		))
 	}
-	if stack.data[0].Equals(conclusion) {
-		return MMError{fmt.Errorf(
+	if stack.data[0] != nil && !stack.data[0].Equals(conclusion) {
+		failure := stack.fail(nil, mmerror.ConclusionMismatch, fmt.Errorf(
 			"Stack entry %v does not match proved asserion %v",
 			stack.data[0],
 			conclusion,
-		)}
+		))
+		failure.Expected = conclusion
+		failure.Actual = stack.data[0]
+		return failure
+	}
+	if missing := countUnknownSteps(proof); missing > 0 {
+		return MMError{Code: mmerror.IncompleteProof, err: fmt.Errorf("proof is incomplete, unknown steps: %d", missing)}
 	}
-	Vprint(3, "Correct proof!")
+	self.proofLog.Log(self.logCtx(), slog.LevelDebug, "correct proof", slog.String("label", string(self.current)))
 	return nil
 }
 
@@ -338,13 +734,7 @@ func (self *MM) Dump() {
 }
 
 func (self *MM) CheckString(content string) error {
-	tokens := strings.Fields(content)
-	input := [][]string{tokens}
-	toks, err := NewToks("", input)
-	if err != nil {
-		return fmt.Errorf("checking string: newtoks: %w", err)
-	}
-	err = self.Read(toks)
+	err := self.Read(NewStringToks(content))
 	if err == nil {
 		return nil
 	}
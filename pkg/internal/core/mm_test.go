@@ -1,6 +1,11 @@
 package core
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
 
 func TestAddC(t *testing.T) {
 	t.Parallel()
@@ -24,3 +29,86 @@ func TestAddC(t *testing.T) {
 		t.Error("LookupSymbolByName failed")
 	}
 }
+
+func TestMM_ScopeRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		database string
+		code     mmerror.Code
+	}{
+		{"constant in a block", "${ $c a $. $}", mmerror.UnexpectedToken},
+		{"constant after a variable", "${ $v a $. $} $c a $.", mmerror.DuplicateSymbol},
+		{"variable after a constant", "$c a $. ${ $v a $. $}", mmerror.DuplicateSymbol},
+		{"constant named like a label", "$c a $. x $a a $. $c x $.", mmerror.DuplicateSymbol},
+		{"variable named like a label", "$c a $. x $a a $. $v x $.", mmerror.DuplicateSymbol},
+		{"label named like a constant", "$c a $. a $a a $.", mmerror.DuplicateSymbol},
+		{"label named like a variable", "$c a $. ${ $v x $. $} x $a a $.", mmerror.DuplicateSymbol},
+		{"variable retyped", "$c a b $. $v x $. ${ ax $f a x $. $} bx $f b x $.", mmerror.DuplicateSymbol},
+		{"disjoint constant", "$c a $. $v x $. $d x a $.", mmerror.MalformedStatement},
+		{"disjoint repeated", "$v x y $. $d x y x $.", mmerror.MalformedStatement},
+		{"stray block close", "$c a $. $} $c b $.", mmerror.UnexpectedToken},
+		{"variable typed again", "$c a $. ${ $v x $. ax $f a x $. $} ${ $v x $. ax2 $f a x $. $}", ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := NewMM(nil).CheckString(tt.database)
+			if tt.code == "" && err != nil {
+				t.Error(err)
+			}
+			if tt.code != "" && !errors.Is(err, tt.code) {
+				t.Errorf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestMM_LexicalRules(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		database string
+		code     mmerror.Code
+	}{
+		{"dollar in constant", "$c a$b $.", mmerror.UnexpectedToken},
+		{"dollar in variable", "$v x$ $.", mmerror.UnexpectedToken},
+		{"colon in label", "$c a $. x:y $a a $.", mmerror.UnexpectedToken},
+		{"label characters", "$c a $. Ab0-_. $a a $.", ""},
+		{"symbol characters", "$c |- ( ) -> ~ [ ] $.", ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := NewMM(nil).CheckString(tt.database)
+			if tt.code == "" && err != nil {
+				t.Error(err)
+			}
+			if tt.code != "" && !errors.Is(err, tt.code) {
+				t.Errorf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestMM_StrayBlockClose(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMMWithOptions(Options{Recover: true})
+	err := mm.CheckString("$c wff $. $v ph $. wph $f wff ph $. $} bad $p wff ph $= wph wph $.")
+	if len(mm.Errors) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(mm.Errors), err)
+	}
+	if !errors.Is(mm.Errors[0], mmerror.UnexpectedToken) {
+		t.Errorf("want an unexpected token first, got %v", mm.Errors[0])
+	}
+	if AsVerifyError(mm.Errors[1]) == nil {
+		t.Errorf("proof after the stray $} was not checked: %v", mm.Errors[1])
+	}
+}
@@ -35,6 +35,7 @@ package core
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 )
 
@@ -44,8 +45,8 @@ func CheckString(content string) error {
 }
 
 func main() {
-	Vprint(1, "mmverify.go -- port of mmverifier.py")
-	mm := NewMM(nil)
+	mm := NewMMWithOptions(Options{Logger: slog.Default()})
+	slog.Info("mmverify.go -- port of mmverifier.py")
 	dbFile := os.Args[1]
 	toks, err := NewToks(dbFile, nil)
 	if err != nil {
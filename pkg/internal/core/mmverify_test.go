@@ -53,10 +53,12 @@ func TestTinyMetamathDatabase(t *testing.T) {
 		t.Error("failed to add variable")
 	}
 	must(mm.AddF("wff", "ph", "wph"))
+	mm.Labels["wph"] = &FullStmt{SType: "$f", MStmt: &Stmt{"wff", "ph"}}
 	if label := mm.FS.LookupF("ph"); string(*label) != "wph" {
 		t.Errorf("failed to add hypothesis")
 	}
 	mm.FS.AddE(Stmt{"|-", "ph"}, "idi.1")
+	mm.Labels["idi.1"] = &FullStmt{SType: "$e", MStmt: &Stmt{"|-", "ph"}}
 	if v, ok := mm.FS.LastFrame().ELabels[ToSymbols(Stmt{"|-", "ph"})]; !ok || v != "idi.1" {
 		t.Error("adding essential hypothesis failed")
 	}
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// mutationDatabase is valid, and every proof and every $d restriction of a
+// theorem in it is needed: no mutant of it proves the same thing.
+const mutationDatabase = `$c ( ) -> wff |- set = $.
+$v ph ps ch x y z $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+vx $f set x $.
+vy $f set y $.
+vz $f set z $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+ax-1 $a |- ( ph -> ( ps -> ph ) ) $.
+ax-2 $a |- ( ( ph -> ( ps -> ch ) ) -> ( ( ph -> ps ) -> ( ph -> ch ) ) ) $.
+${
+  $d x y $.
+  ax-dv $a |- x = y $.
+$}
+${
+  a1i.1 $e |- ph $.
+  a1i $p |- ( ps -> ph ) $= wph wps wph wi a1i.1 wph wps ax-1 ax-mp $.
+$}
+${
+  a1d.1 $e |- ( ph -> ps ) $.
+  a1d $p |- ( ph -> ( ph -> ps ) ) $= ( wi ax-1 ax-mp ) ABDZAGDCGAEF $.
+$}
+id $p |- ( ph -> ph ) $= wph wph wph wi wi wph wph wi wph wph ax-1 wph wph wph
+  wi wph wi wi wph wph wph wi wi wph wph wi wi wph wph wph wi ax-1 wph wph wph
+  wi wph ax-2 ax-mp ax-mp $.
+${
+  $d x z $.
+  dv $p |- x = z $= vx vz ax-dv $.
+$}
+${
+  $d y z $.
+  dvc $p |- z = y $= ( ax-dv ) BAC $.
+$}
+`
+
+// knownSurvivors lists the kinds of mutant the checker does not reject yet, and
+// why. A kind whose mutants are all rejected must be removed from the list.
+var knownSurvivors = map[string]string{}
+
+// mutant is a changed proof, or a changed database.
+type mutant struct {
+	kind string
+	// What was changed, to report the mutant.
+	change string
+	text   []string
+}
+
+// proofMutants returns every mutant of proof. hypotheses are the labels of the
+// hypotheses active where the proof is.
+func proofMutants(proof []string, hypotheses []Label) []mutant {
+	var out []mutant
+
+	steps := proof
+	offset := 0
+	if proof[0] == "(" {
+		end, _ := FindEndOfProofBlock(proof)
+		steps = proof[1:end]
+		offset = 1
+	}
+
+	// Labels, either the steps of a normal proof or the list of a compressed one.
+	for i := range steps {
+		for j := i + 1; j < len(steps); j++ {
+			if steps[i] != steps[j] {
+				out = append(out, mutant{"swap labels", fmt.Sprintf("swap %s and %s", steps[i], steps[j]), swap(proof, offset+i, offset+j)})
+			}
+		}
+		out = append(out, mutant{"drop step", fmt.Sprintf("drop %s", steps[i]), remove(proof, offset+i, 1)})
+		if !isHypothesis(Label(steps[i]), hypotheses) {
+			continue
+		}
+		for _, hypothesis := range hypotheses {
+			if Label(steps[i]) != hypothesis {
+				out = append(out, mutant{"replace hypothesis", fmt.Sprintf("replace %s with %s", steps[i], hypothesis), replace(proof, offset+i, string(hypothesis))})
+			}
+		}
+	}
+	if proof[0] != "(" {
+		return out
+	}
+
+	// The letters of a compressed proof, taken as one word.
+	letters := strings.Join(proof[len(steps)+2:], "")
+	head := proof[:len(steps)+2]
+	for i := range letters {
+		out = append(out, mutant{"drop step", fmt.Sprintf("drop letter %d", i), append(clone(head), letters[:i]+letters[i+1:])})
+		for _, ch := range "ABCDEFGHIJKLMNOPQRSTZ" {
+			if byte(ch) != letters[i] {
+				changed := letters[:i] + string(ch) + letters[i+1:]
+				out = append(out, mutant{"change letter", fmt.Sprintf("change letter %d to %c", i, ch), append(clone(head), changed)})
+			}
+		}
+	}
+	return out
+}
+
+func clone(words []string) []string {
+	return append([]string(nil), words...)
+}
+
+func swap(words []string, i int, j int) []string {
+	out := clone(words)
+	out[i], out[j] = out[j], out[i]
+	return out
+}
+
+func remove(words []string, i int, n int) []string {
+	return append(clone(words[:i]), words[i+n:]...)
+}
+
+func replace(words []string, i int, word string) []string {
+	out := clone(words)
+	out[i] = word
+	return out
+}
+
+func isHypothesis(label Label, hypotheses []Label) bool {
+	for _, hypothesis := range hypotheses {
+		if label == hypothesis {
+			return true
+		}
+	}
+	return false
+}
+
+// mutationVisitor verifies the mutants of each proof where the proof is, so
+// that its hypotheses are active.
+type mutationVisitor struct {
+	NopVisitor
+	t  *testing.T
+	mm *MM
+	// The number of mutants of each kind.
+	mutants map[string]int
+}
+
+func (v *mutationVisitor) OnTheorem(label Label, assertion *Assertion, proof []string, result Result) error {
+	if result.Err != nil {
+		v.t.Errorf("%s: the original proof fails: %v", label, result.Err)
+		return nil
+	}
+	var hypotheses []Label
+	for _, frame := range v.mm.FS.Frames {
+		for _, label := range frame.FLabels {
+			hypotheses = append(hypotheses, label)
+		}
+		for _, label := range frame.ELabels {
+			hypotheses = append(hypotheses, label)
+		}
+	}
+	sort.Slice(hypotheses, func(i, j int) bool { return hypotheses[i] < hypotheses[j] })
+
+	for _, m := range proofMutants(proof, hypotheses) {
+		v.mutants[m.kind]++
+		if err := v.mm.Verify(assertion.Hyps, assertion.S, m.text); err == nil {
+			v.t.Errorf("%s: mutant passes (%s): %s", label, m.change, strings.Join(m.text, " "))
+		}
+	}
+	return nil
+}
+
+func TestMutation_Proofs(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMM(nil)
+	visitor := &mutationVisitor{t: t, mm: mm, mutants: map[string]int{}}
+	mm.Visitor = visitor
+	if err := mm.CheckString(mutationDatabase); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{"swap labels", "drop step", "replace hypothesis", "change letter"} {
+		if visitor.mutants[kind] == 0 {
+			t.Errorf("no %q mutants", kind)
+		}
+	}
+}
+
+// disjointMutants returns the mutants of database that delete or weaken one of
+// the $d statements in the scope of a theorem.
+func disjointMutants(database string) []mutant {
+	var out []mutant
+	lines := strings.Split(database, "\n")
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "$d" || !theoremInScope(lines[i+1:]) {
+			continue
+		}
+		out = append(out, mutant{"delete $d", "delete " + line, remove(lines, i, 1)})
+		weakened := strings.Join(remove(fields, len(fields)-2, 1), " ")
+		out = append(out, mutant{"weaken $d", fmt.Sprintf("replace %s with %s", line, weakened), replace(lines, i, weakened)})
+	}
+	return out
+}
+
+// theoremInScope reports whether a $p statement follows in lines before the
+// current block closes.
+func theoremInScope(lines []string) bool {
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) > 1 && fields[1] == "$p":
+			return true
+		case len(fields) > 0 && fields[0] == "$}":
+			return false
+		}
+	}
+	return false
+}
+
+func TestMutation_Disjoint(t *testing.T) {
+	t.Parallel()
+
+	mutants := disjointMutants(mutationDatabase)
+	if len(mutants) == 0 {
+		t.Fatal("no $d mutants")
+	}
+	survivors := map[string]int{}
+	for _, m := range mutants {
+		err := NewMM(nil).CheckString(strings.Join(m.text, "\n"))
+		if errors.Is(err, mmerror.DisjointViolation) {
+			continue
+		}
+		survivors[m.kind]++
+		if _, known := knownSurvivors[m.kind]; !known {
+			t.Errorf("mutant passes (%s): %v", m.change, err)
+		}
+	}
+	for kind, reason := range knownSurvivors {
+		if survivors[kind] == 0 {
+			t.Errorf("every %q mutant is rejected now, remove it from knownSurvivors (%s)", kind, reason)
+		}
+	}
+}
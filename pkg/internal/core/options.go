@@ -0,0 +1,97 @@
+package core
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Options configures a single MM. The zero value verifies every proof and
+// logs nothing.
+type Options struct {
+	// Logger receives structured log records. Nil discards them.
+	Logger *slog.Logger
+	// LogLevels overrides the minimum level of Logger for individual
+	// subsystems (SubsystemReader, SubsystemToks, SubsystemProof).
+	LogLevels map[string]slog.Level
+	// Proofs are only checked from BeginLabel on, and reading stops at
+	// EndLabel.
+	BeginLabel *Label
+	EndLabel   *Label
+	// Selection further limits which proofs are checked.
+	Selection Selection
+	Limits    Limits
+	// If positive, each proof must be verified within this time.
+	ProofTimeout time.Duration
+	// Optional. Called after each statement, but no more often than
+	// ProgressInterval, and once more when reading stops.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+	// ParseOnly reads the database without checking any proofs.
+	ParseOnly bool
+	// DisableIncludes makes $[ $] statements an error.
+	DisableIncludes bool
+	// Visitor, if set, receives a callback for each statement.
+	Visitor Visitor
+	// Recover keeps reading after a statement fails, collecting every
+	// diagnostic in MM.Errors, and stops after MaxErrors of them if that
+	// is positive. Read then returns them joined together.
+	Recover   bool
+	MaxErrors int
+	// AllowIncomplete keeps a proof with unknown steps, written ?, from
+	// failing its statement. It is still recorded as incomplete in
+	// MM.Results.
+	AllowIncomplete bool
+}
+
+// Limits bound the work done by an MM, for databases that are not trusted.
+// Zero means unlimited. Going over a limit is an MMError with the code
+// mmerror.LimitExceeded, which Recover skips like any other, except for
+// MaxTokens, which ends the run.
+type Limits struct {
+	// Maximum number of labels in a single proof.
+	MaxProofLength int
+	// Maximum number of files pulled in by $[ $], and how deeply they may
+	// include each other.
+	MaxIncludes     int
+	MaxIncludeDepth int
+	// Maximum number of tokens read, comments included, across all files.
+	MaxTokens int
+	// Maximum number of symbols in a statement other than a proof.
+	MaxStatementLength int
+	// Maximum number of bytes in a line of a file, not counting the
+	// newline. Longer lines are skipped without being held in memory.
+	MaxLineLength int
+	// Maximum number of entries on the proof stack.
+	MaxStackDepth int
+	// Maximum number of symbols in a statement built by substitution
+	// during a proof.
+	MaxSubstitutionLength int
+}
+
+func NewMMWithOptions(opts Options) *MM {
+	return &MM{
+		BeginLabel:       opts.BeginLabel,
+		EndLabel:         opts.EndLabel,
+		Constants:        map[string]TUnit{},
+		Variables:        map[string]TUnit{},
+		typecodes:        map[string]string{},
+		Labels:           map[Label]*FullStmt{},
+		labelPos:         map[Label]Pos{},
+		VerifyProofs:     opts.BeginLabel == nil && !opts.ParseOnly,
+		FS:               NewFrameStack(),
+		Visitor:          opts.Visitor,
+		Limits:           opts.Limits,
+		ProofTimeout:     opts.ProofTimeout,
+		Progress:         opts.Progress,
+		ProgressInterval: opts.ProgressInterval,
+		Recover:          opts.Recover,
+		MaxErrors:        opts.MaxErrors,
+		AllowIncomplete:  opts.AllowIncomplete,
+		log:              subsystemLogger(opts.Logger, opts.LogLevels, SubsystemReader),
+		toksLog:          subsystemLogger(opts.Logger, opts.LogLevels, SubsystemToks),
+		proofLog:         subsystemLogger(opts.Logger, opts.LogLevels, SubsystemProof),
+		parseOnly:        opts.ParseOnly,
+		selector:         newSelector(opts.Selection),
+		noIncludes:       opts.DisableIncludes,
+	}
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// TestOptions_Independent checks that two MMs with different settings can
+// run at the same time without sharing trace output.
+func TestOptions_Independent(t *testing.T) {
+	t.Parallel()
+
+	var quiet, loud bytes.Buffer
+	mms := []*MM{
+		NewMMWithOptions(Options{Logger: slog.New(slog.NewTextHandler(&quiet, &slog.HandlerOptions{Level: slog.LevelWarn}))}),
+		NewMMWithOptions(Options{Logger: slog.New(slog.NewTextHandler(&loud, &slog.HandlerOptions{Level: slog.LevelDebug}))}),
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(mms))
+	for i, mm := range mms {
+		wg.Add(1)
+		go func(i int, mm *MM) {
+			defer wg.Done()
+			errs[i] = mm.CheckString(visitorDatabase)
+		}(i, mm)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if quiet.Len() != 0 {
+		t.Errorf("quiet MM printed %q", quiet.String())
+	}
+	if !strings.Contains(loud.String(), "correct proof") {
+		t.Errorf("loud MM printed %q", loud.String())
+	}
+}
+
+func TestOptions_ParseOnly(t *testing.T) {
+	t.Parallel()
+
+	begin := Label("idi")
+	mm := NewMMWithOptions(Options{ParseOnly: true, BeginLabel: &begin})
+	if err := mm.CheckString(visitorDatabase); err != nil {
+		t.Fatal(err)
+	}
+	if mm.Results[len(mm.Results)-1].Checked {
+		t.Error("proof was checked in parse-only mode")
+	}
+}
+
+func TestOptions_MaxProofLength(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMMWithOptions(Options{Limits: Limits{MaxProofLength: 2}})
+	err := mm.CheckString(visitorDatabase)
+	if err == nil || !strings.Contains(err.Error(), "more than the limit of 2") {
+		t.Errorf("expected proof length error, got %v", err)
+	}
+}
+
+const recoverDatabase = `$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+bad1 $p wff ph $= wph wph $.
+bad2 $a |- ps $.
+uses1 $p wff ph $= wph bad1 $.
+`
+
+func TestOptions_Recover(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMMWithOptions(Options{Recover: true})
+	err := mm.CheckString(recoverDatabase)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	if len(mm.Errors) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(mm.Errors), err)
+	}
+	for i, label := range []Label{"bad1", "bad2"} {
+		m := AsMMError(mm.Errors[i])
+		if m == nil || m.Label != label {
+			t.Errorf("error %d is %v, want one for %s", i, mm.Errors[i], label)
+		}
+	}
+	if AsVerifyError(mm.Errors[0]) == nil || AsVerifyError(mm.Errors[1]) != nil {
+		t.Errorf("want a proof error then a syntax error, got %v", err)
+	}
+	last := mm.Results[len(mm.Results)-1]
+	if last.Label != "uses1" || !last.Checked || last.Err != nil {
+		t.Errorf("theorem using a failed one was not checked: %+v", last)
+	}
+}
+
+func TestOptions_MaxErrors(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMMWithOptions(Options{Recover: true, MaxErrors: 2})
+	err := mm.CheckString(recoverDatabase)
+	if !errors.Is(err, mmerror.TooManyErrors) {
+		t.Fatalf("expected too many errors, got %v", err)
+	}
+	if len(mm.Errors) != 3 {
+		t.Errorf("got %d errors, want 2 and the one that stopped the run", len(mm.Errors))
+	}
+}
+
+func TestOptions_NoRecover(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMM(nil)
+	if err := mm.CheckString(recoverDatabase); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(mm.Errors) != 0 {
+		t.Errorf("errors collected without Recover: %v", mm.Errors)
+	}
+}
+
+const incompleteDatabase = `$c ( ) -> wff |- $.
+$v ph ps $.
+wph $f wff ph $.
+wps $f wff ps $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+`
+
+func TestOptions_AllowIncomplete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		proof   string
+		code    mmerror.Code
+		missing int
+	}{
+		// Nothing is known of the conclusion.
+		{"? ? ? ? ax-mp", mmerror.IncompleteProof, 4},
+		{"wph wps ? ? ax-mp", mmerror.IncompleteProof, 2},
+		{"( ax-mp ) ?A??B", mmerror.IncompleteProof, 3},
+		// The known part of the proof is still checked.
+		{"wph wph ? ? ax-mp", mmerror.ConclusionMismatch, 0},
+		{"wph wps wph ? ax-mp", mmerror.HypothesisMismatch, 0},
+	}
+	for _, tt := range tests {
+		content := incompleteDatabase + "th $p |- ps $= " + tt.proof + " $.\n"
+
+		err := NewMM(nil).CheckString(content)
+		if !errors.Is(err, tt.code) {
+			t.Errorf("%s: expected %s, got %v", tt.proof, tt.code, err)
+		}
+
+		mm := NewMMWithOptions(Options{AllowIncomplete: true})
+		err = mm.CheckString(content)
+		if tt.code != mmerror.IncompleteProof {
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.proof, err)
+			continue
+		}
+		result := mm.Results[len(mm.Results)-1]
+		if !result.Checked || !errors.Is(result.Err, mmerror.IncompleteProof) || result.Missing != tt.missing {
+			t.Errorf("%s: unexpected result %+v", tt.proof, result)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// Pos is a location in a database. File is empty for in-memory input.
+// Line and Col count from 1; Col counts bytes.
+type Pos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (pos Pos) String() string {
+	if pos.File == "" {
+		return fmt.Sprintf("%d:%d", pos.Line, pos.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Col)
+}
+
+// IsValid reports whether pos refers to an actual location.
+func (pos Pos) IsValid() bool {
+	return pos.Line > 0
+}
+
+// Position converts pos to the form used by package mmerror.
+func (pos Pos) Position() mmerror.Position {
+	return mmerror.Position{File: pos.File, Line: pos.Line, Column: pos.Col}
+}
+
+// Token is a single whitespace-separated token and where it starts.
+type Token struct {
+	Text string
+	Pos  Pos
+}
@@ -0,0 +1,40 @@
+package core
+
+import "time"
+
+// Progress is a snapshot of how far Read has got.
+type Progress struct {
+	// Bytes consumed and total bytes of every file opened so far. The
+	// total grows as $[ $] statements pull in more files.
+	BytesRead  int64
+	BytesTotal int64
+	// Statements read, including unlabeled ones such as $c and $d.
+	Statements int
+	// Proofs checked, whether or not they were valid.
+	ProofsVerified int
+	// The most recently read label.
+	Label Label
+	// Done is set on the final report, after reading has stopped.
+	Done bool
+}
+
+// reportProgress calls the progress callback at most once per
+// ProgressInterval, plus once more when done is set.
+func (self *MM) reportProgress(toks *Toks, done bool) {
+	if self.Progress == nil {
+		return
+	}
+	now := time.Now()
+	if !done && self.ProgressInterval > 0 && now.Sub(self.lastProgress) < self.ProgressInterval {
+		return
+	}
+	self.lastProgress = now
+	self.Progress(Progress{
+		BytesRead:      toks.BytesRead,
+		BytesTotal:     toks.BytesTotal,
+		Statements:     self.statements,
+		ProofsVerified: self.proofsVerified,
+		Label:          self.current,
+		Done:           done,
+	})
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.mm")
+	included := "$c |- wff $.\n"
+	rest := "$[ inc.mm $]\n" + visitorDatabase[len("$( header $)\n$c |- wff $.\n"):]
+	if err := os.WriteFile(filepath.Join(dir, "inc.mm"), []byte(included), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(main, []byte(rest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []Progress
+	mm := NewMMWithOptions(Options{Progress: func(p Progress) { reports = append(reports, p) }})
+	toks, err := NewToks(main, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toks.IncludePath = []string{dir}
+	if err := mm.Read(toks); err != nil && !IsEOF(err) {
+		t.Fatal(err)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("expected several reports, got %v", reports)
+	}
+	last := reports[len(reports)-1]
+	wantBytes := int64(len(included) + len(rest))
+	if !last.Done || last.BytesRead != wantBytes || last.BytesTotal != wantBytes {
+		t.Errorf("unexpected final report %+v, want %d bytes", last, wantBytes)
+	}
+	if last.ProofsVerified != 1 || last.Label != "idi" {
+		t.Errorf("unexpected final report %+v", last)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Statements < reports[i-1].Statements || reports[i].BytesRead < reports[i-1].BytesRead {
+			t.Errorf("progress went backwards: %+v then %+v", reports[i-1], reports[i])
+		}
+	}
+}
@@ -1,7 +1,15 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"log/slog"
 
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// ProofStack holds the statements proved so far. A nil entry is the result of
+// an unknown step, written ?: it stands for any statement, so it matches any
+// hypothesis, and what an assertion proves from it may be unknown too.
 type ProofStack struct {
 	data []Stmt
 }
@@ -11,10 +19,14 @@ func NewProofStack() *ProofStack {
 }
 
 func (stack *ProofStack) TreatStep(mm *MM, step *FullStmt) error {
-	Vprint(10, "Proof step:", fmt.Sprintf("%v", step))
+	if mm.tracing(mm.proofLog) {
+		mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "proof step", slog.String("type", step.SType), slog.Int("depth", len(stack.data)))
+	}
 	if IsHypothesis(*step) {
 		stmt := *step.MStmt
-		stack.data = append(stack.data, stmt)
+		if err := stack.push(mm, stmt); err != nil {
+			return err
+		}
 		return nil
 	}
 	if !IsAssertion(*step) {
@@ -22,53 +34,135 @@ func (stack *ProofStack) TreatStep(mm *MM, step *FullStmt) error {
 	}
 	assertion := *step.MAssertion
 	dvs0 := assertion.Dvs
-	fhyps0 := assertion.F
-	ehyps0 := assertion.E
+	hyps0 := assertion.Hyps
 	conclusion0 := assertion.S
-	npop := len(fhyps0) + len(ehyps0)
+	npop := len(hyps0)
 	sp := len(stack.data) - npop
 	if sp < 0 {
-		return MMError{fmt.Errorf("Stack underflow: proof step %v requires too many hypotehses %v", step, npop)}
+		return stack.fail(nil, mmerror.StackUnderflow, fmt.Errorf("Stack underflow: proof step %v requires too many hypotehses %v", step, npop))
 	}
+	// $f and $e hypotheses may come in any order, so the substitution is
+	// built from the $f ones before any $e one is checked against it.
 	subst := map[string]Stmt{}
-	for _, p := range fhyps0 {
-		typecode := p.Typecode
-		va := p.V
-		entry := stack.data[sp]
+	for i, hyp := range hyps0 {
+		if hyp.F == nil {
+			continue
+		}
+		typecode := hyp.F.Typecode
+		va := hyp.F.V
+		entry := stack.data[sp+i]
+		if entry == nil {
+			subst[va] = nil
+			continue
+		}
 		if entry[0] != typecode {
-			return MMError{fmt.Errorf("Proof stack entry %v does not match floating hypothesis %v %v", entry, typecode, va)}
+			failure := stack.fail(subst, mmerror.HypothesisMismatch, fmt.Errorf("Proof stack entry %v does not match floating hypothesis %v %v", entry, typecode, va))
+			failure.Expected = Stmt{typecode, va}
+			failure.Actual = entry
+			return failure
 		}
 		subst[va] = entry[1:]
-		sp += 1
 	}
-	Vprint(15, "Substitution to apply", fmt.Sprintf("%v", subst))
-	for _, h := range ehyps0 {
-		entry := stack.data[sp]
+	if mm.tracing(mm.proofLog) {
+		mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "substitution to apply", slog.Any("subst", subst))
+	}
+	for i, hyp := range hyps0 {
+		if hyp.F != nil {
+			continue
+		}
+		h := hyp.E
+		entry := stack.data[sp+i]
+		if entry == nil || !substKnown(Stmt(h), subst) {
+			continue
+		}
 		substH := ApplySubst(Stmt(h), subst)
-		if Stmt(entry).Equals(substH) {
-			return MMError{fmt.Errorf("Proof stack entry %v does not match essential hypothesis %v", entry, substH)}
+		if err := mm.checkSubstLength(substH); err != nil {
+			return err
+		}
+		if mm.tracing(mm.proofLog) {
+			mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "apply substitution", slog.Any("stmt", Stmt(h)), slog.Any("result", substH))
+		}
+		if !Stmt(entry).Equals(substH) {
+			failure := stack.fail(subst, mmerror.HypothesisMismatch, fmt.Errorf("Proof stack entry %v does not match essential hypothesis %v", entry, substH))
+			failure.Expected = substH
+			failure.Actual = entry
+			return failure
 		}
-		sp += 1
 	}
 	for p, _ := range dvs0 {
 		x := p.First
 		y := p.Second
-		Vprint(16, "dist", x, y, subst[x].String(), subst[y].String())
+		if mm.tracing(mm.proofLog) {
+			mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "check disjoint", slog.String("x", x), slog.String("y", y), slog.Any("x_subst", subst[x]), slog.Any("y_subst", subst[y]))
+		}
 		xVars := mm.FS.FindVars(subst[x])
 		yVars := mm.FS.FindVars(subst[y])
 		for x0, _ := range xVars {
 			for y0, _ := range yVars {
+				var err error
 				if x0 == y0 {
-					return MMError{fmt.Errorf("new disjoint violation: %q", x0)}
+					err = fmt.Errorf("new disjoint violation: %q", x0)
+				} else if !mm.FS.LookupD(x0, y0) {
+					err = fmt.Errorf("variables %q and %q are not known to be disjoint", x0, y0)
 				}
-				if !mm.FS.LookupD(x0, y0) {
-					return MMError{fmt.Errorf("variables %q and %q are not known to be disjoint", x0, y0)}
+				if err != nil {
+					failure := stack.fail(subst, mmerror.DisjointViolation, err)
+					failure.Disjoint = &Dv{First: x, Second: y}
+					failure.DisjointVars = [2]string{x0, y0}
+					return failure
 				}
 			}
 		}
 	}
 	stack.data = stack.data[:len(stack.data)-npop]
+	if !substKnown(conclusion0, subst) {
+		return stack.push(mm, nil)
+	}
 	newStmt := ApplySubst(conclusion0, subst)
-	stack.data = append(stack.data, newStmt)
+	if mm.tracing(mm.proofLog) {
+		mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "apply substitution", slog.Any("stmt", conclusion0), slog.Any("result", newStmt))
+	}
+	if err := mm.checkSubstLength(newStmt); err != nil {
+		return err
+	}
+	return stack.push(mm, newStmt)
+}
+
+// substKnown reports whether every variable of stmt is replaced by a known
+// statement in subst.
+func substKnown(stmt Stmt, subst map[string]Stmt) bool {
+	for _, tok := range stmt {
+		if newThing, ok := mapHasVar(subst, tok); ok && newThing == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// push adds stmt to the top of the stack, unless that would make the stack
+// deeper than Limits.MaxStackDepth.
+func (stack *ProofStack) push(mm *MM, stmt Stmt) error {
+	if limit := mm.Limits.MaxStackDepth; limit > 0 && len(stack.data) >= limit {
+		return MMError{Code: mmerror.LimitExceeded, err: fmt.Errorf("proof stack is deeper than the limit of %d", limit)}
+	}
+	stack.data = append(stack.data, stmt)
 	return nil
 }
+
+// checkSubstLength fails if a statement built by substitution is longer
+// than Limits.MaxSubstitutionLength.
+func (self *MM) checkSubstLength(stmt Stmt) error {
+	if limit := self.Limits.MaxSubstitutionLength; limit > 0 && len(stmt) > limit {
+		return MMError{Code: mmerror.LimitExceeded, err: fmt.Errorf("substitution gives a statement of %d symbols, more than the limit of %d", len(stmt), limit)}
+	}
+	return nil
+}
+
+// fail describes a step that does not check, keeping a copy of the stack.
+func (stack *ProofStack) fail(subst map[string]Stmt, code mmerror.Code, err error) ProofFailure {
+	return ProofFailure{
+		Stack: append([]Stmt(nil), stack.data...),
+		Subst: subst,
+		err:   MMError{Code: code, err: err},
+	}
+}
@@ -0,0 +1,20 @@
+package core
+
+// Result records what happened to one labeled statement during Read.
+type Result struct {
+	Label Label
+	// One of "$a", "$p", "$e", "$f".
+	SType string
+	Pos   Pos
+	// Checked is true when the proof of a $p statement was verified,
+	// successfully or not.
+	Checked bool
+	// Selected is true for a $p statement in the Selection, whether or not
+	// proofs were being checked.
+	Selected bool
+	// Err is the verification error of a $p statement, if any.
+	Err error
+	// Missing is the number of unknown steps of an incomplete proof, whose
+	// Err is an mmerror.IncompleteProof.
+	Missing int
+}
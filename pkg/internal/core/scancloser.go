@@ -2,59 +2,201 @@ package core
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"strings"
+	"unicode"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
 )
 
 type ScanCloser struct {
 	isMemoryCloser bool
-	tokens         [][]string
+	lines          [][]Token
 	path           string
 	fh             *os.File
-	scanner        *bufio.Scanner
+	// A bufio.Reader rather than a bufio.Scanner, which cannot read
+	// lines longer than 64KB.
+	reader *bufio.Reader
+	// err is the first error reading fh, other than end of file.
+	err error
+	// maxLineLength is the longest line, in bytes and without its
+	// newline, that Line reads from fh; zero means no limit. A longer
+	// line is skipped and longLine set until Err returns it.
+	maxLineLength int
+	longLine      error
+	// line is the number of the line most recently returned by Text.
+	line int
+	// size is the total number of bytes in the source and lastLen the
+	// number of bytes in the line most recently returned by Text. Both
+	// stay zero for the leftovers of a line put back by Toks.Readf, whose
+	// bytes have already been counted.
+	size    int64
+	lastLen int64
+	counted bool
 }
 
 func NewScanCloser(path string, tokens [][]string) (*ScanCloser, error) {
 	if path != "" && len(tokens) != 0 {
-		return nil, MMError{errors.New("ScanCloser can either be in memory or from a path on disk, not both")}
+		return nil, MMError{err: errors.New("ScanCloser can either be in memory or from a path on disk, not both")}
 	}
 	if len(tokens) != 0 {
+		var size int64
+		lines := make([][]Token, len(tokens))
+		for i, line := range tokens {
+			lines[i] = memoryLineTokens(i+1, line)
+			size += tokensLineLen(lines[i])
+		}
 		return &ScanCloser{
 			isMemoryCloser: true,
-			tokens:         tokens,
+			lines:          lines,
+			size:           size,
+			counted:        true,
 		}, nil
 	}
 	fh, err := os.Open(path)
 	if err != nil {
 		return nil, IOError{err}
 	}
+	var size int64
+	if info, err := fh.Stat(); err == nil {
+		size = info.Size()
+	}
 	return &ScanCloser{
 		path:    path,
 		fh:      fh,
-		scanner: bufio.NewScanner(fh),
+		reader:  bufio.NewReader(fh),
+		size:    size,
+		counted: true,
 	}, nil
 }
 
-func (scanCloser *ScanCloser) Text() StringListOption {
+// NewStringScanCloser makes an in-memory ScanCloser over the text of a
+// database, keeping the columns of its tokens.
+func NewStringScanCloser(content string) *ScanCloser {
+	var size int64
+	var lines [][]Token
+	for i, line := range strings.Split(content, "\n") {
+		tokens := splitLine("", i+1, line)
+		lines = append(lines, tokens)
+		size += tokensLineLen(tokens)
+	}
+	return &ScanCloser{
+		isMemoryCloser: true,
+		lines:          lines,
+		size:           size,
+		counted:        true,
+	}
+}
+
+// newMemoryScanCloser makes an in-memory ScanCloser holding a single line
+// of tokens that already know their positions.
+func newMemoryScanCloser(tokens []Token) *ScanCloser {
+	return &ScanCloser{
+		isMemoryCloser: true,
+		lines:          [][]Token{tokens},
+	}
+}
+
+// Pos returns the position of the start of the line most recently
+// returned by Text.
+func (scanCloser *ScanCloser) Pos() Pos {
+	return Pos{File: scanCloser.path, Line: scanCloser.line, Col: 1}
+}
+
+// Line returns the tokens of the next line, each with its position, and
+// false once there are no more lines.
+func (scanCloser *ScanCloser) Line() ([]Token, bool) {
 	// Control does not leave this block if we enter it.
 	if scanCloser.isMemoryCloser {
-		if len(scanCloser.tokens) == 0 {
-			return StringListOption{}
+		if len(scanCloser.lines) == 0 {
+			return nil, false
+		}
+		out := scanCloser.lines[0]
+		scanCloser.lines = scanCloser.lines[1:]
+		scanCloser.line++
+		if scanCloser.counted {
+			scanCloser.lastLen = tokensLineLen(out)
+		}
+		return out, true
+	}
+
+	if scanCloser.reader == nil || scanCloser.err != nil {
+		return nil, false
+	}
+	text, n, tooLong, err := scanCloser.readLine()
+	if err != nil {
+		scanCloser.err = IOError{err}
+		return nil, false
+	}
+	if n == 0 {
+		return nil, false
+	}
+	scanCloser.line++
+	scanCloser.lastLen = n
+	if tooLong {
+		scanCloser.longLine = MMError{
+			Code: mmerror.LimitExceeded,
+			err:  fmt.Errorf("%w of %d bytes", errLineLimit, scanCloser.maxLineLength),
+			Pos:  scanCloser.Pos(),
 		}
-		out := scanCloser.tokens[-1+len(scanCloser.tokens)]
-		scanCloser.tokens = scanCloser.tokens[:-1+len(scanCloser.tokens)]
-		return StringListOption{Just: true, Data: out}
+		return nil, false
 	}
+	return splitLine(scanCloser.path, scanCloser.line, text), true
+}
 
-	ok := scanCloser.scanner.Scan()
+// readLine reads the next line from fh without its newline, and the number
+// of bytes it took up. A line over maxLineLength is read through to its end
+// in pieces, so that it never has to fit in memory, and its text dropped.
+func (scanCloser *ScanCloser) readLine() (string, int64, bool, error) {
+	var buf []byte
+	var n int64
+	tooLong := false
+	for {
+		chunk, err := scanCloser.reader.ReadSlice('\n')
+		n += int64(len(chunk))
+		if !tooLong {
+			buf = append(buf, chunk...)
+			limit := scanCloser.maxLineLength
+			if limit > 0 && len(bytes.TrimSuffix(buf, []byte("\n"))) > limit {
+				tooLong = true
+				buf = nil
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", n, tooLong, err
+		}
+		return strings.TrimSuffix(string(buf), "\n"), n, tooLong, nil
+	}
+}
+
+// Err returns the error that made Line stop early, or nil at end of file.
+// A line over the length limit is reported once, and Line then carries on
+// with the line after it.
+func (scanCloser *ScanCloser) Err() error {
+	if err := scanCloser.longLine; err != nil {
+		scanCloser.longLine = nil
+		return err
+	}
+	return scanCloser.err
+}
+
+func (scanCloser *ScanCloser) Text() StringListOption {
+	tokens, ok := scanCloser.Line()
 	if !ok {
 		return StringListOption{}
 	}
-	return StringListOption{
-		Just: true,
-		Data: strings.Fields(scanCloser.scanner.Text()),
+	out := make([]string, len(tokens))
+	for i, tok := range tokens {
+		out[i] = tok.Text
 	}
+	return StringListOption{Just: true, Data: out}
 }
 
 func (scanCloser *ScanCloser) MustClose() {
@@ -65,5 +207,53 @@ func (scanCloser *ScanCloser) MustClose() {
 		panic(err)
 	}
 	scanCloser.fh = nil
-	scanCloser.scanner = nil
+	scanCloser.reader = nil
+}
+
+// splitLine splits a line into whitespace-separated tokens like
+// strings.Fields, recording the byte column at which each one starts.
+func splitLine(path string, line int, text string) []Token {
+	var out []Token
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				out = append(out, Token{Text: text[start:i], Pos: Pos{File: path, Line: line, Col: start + 1}})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		out = append(out, Token{Text: text[start:], Pos: Pos{File: path, Line: line, Col: start + 1}})
+	}
+	return out
+}
+
+// memoryLineTokens positions an in-memory line as if it had been written
+// out with single spaces between tokens.
+func memoryLineTokens(line int, tokens []string) []Token {
+	out := make([]Token, len(tokens))
+	col := 1
+	for i, tok := range tokens {
+		out[i] = Token{Text: tok, Pos: Pos{Line: line, Col: col}}
+		col += len(tok) + 1
+	}
+	return out
+}
+
+// tokensLineLen is the length of a line of tokens as if it had been
+// written out with single spaces and a trailing newline.
+func tokensLineLen(line []Token) int64 {
+	n := int64(len(line))
+	for _, tok := range line {
+		n += int64(len(tok.Text))
+	}
+	if n == 0 {
+		return 1
+	}
+	return n
 }
@@ -34,3 +34,34 @@ func TestScanCloser(t *testing.T) {
 
 	scanCloser.MustClose()
 }
+
+// TestScanCloserColumns tests that tokens keep the column they start at.
+func TestScanCloserColumns(t *testing.T) {
+	t.Parallel()
+
+	scanCloser := NewStringScanCloser("$c  a\n\tb $.")
+
+	var got []Token
+	for {
+		line, ok := scanCloser.Line()
+		if !ok {
+			break
+		}
+		got = append(got, line...)
+	}
+
+	want := []Token{
+		{Text: "$c", Pos: Pos{Line: 1, Col: 1}},
+		{Text: "a", Pos: Pos{Line: 1, Col: 5}},
+		{Text: "b", Pos: Pos{Line: 2, Col: 2}},
+		{Text: "$.", Pos: Pos{Line: 2, Col: 4}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("token %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// Selection chooses the $p statements whose proofs are checked. A statement
+// is selected if it matches any of the criteria, and the zero Selection
+// selects every statement. Statements outside the selection are still read.
+type Selection struct {
+	Ranges []LabelRange
+	// Globs are path.Match patterns, such as "syl*".
+	Globs []string
+	// Patterns match anywhere in a label unless they are anchored.
+	Patterns []*regexp.Regexp
+	Labels   []Label
+}
+
+// LabelRange is an inclusive range of labels, in the order they appear. An
+// empty First starts at the beginning of the database and an empty Last
+// runs to its end.
+type LabelRange struct {
+	First Label
+	Last  Label
+}
+
+func (self Selection) IsEmpty() bool {
+	return len(self.Ranges) == 0 && len(self.Globs) == 0 && len(self.Patterns) == 0 && len(self.Labels) == 0
+}
+
+// Validate returns an error for a malformed glob.
+func (self Selection) Validate() error {
+	for _, glob := range self.Globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("glob %q: %w", glob, err)
+		}
+	}
+	return nil
+}
+
+// Where the labels read so far are relative to a LabelRange.
+const (
+	rangeBefore int8 = iota
+	rangeInside
+	rangeAfter
+)
+
+// selector applies a Selection to the labels of a database, read in order.
+type selector struct {
+	sel    Selection
+	labels map[Label]TUnit
+	ranges []int8
+}
+
+func newSelector(sel Selection) *selector {
+	if sel.IsEmpty() {
+		return nil
+	}
+	labels := map[Label]TUnit{}
+	for _, label := range sel.Labels {
+		labels[label] = Unit
+	}
+	return &selector{sel: sel, labels: labels, ranges: make([]int8, len(sel.Ranges))}
+}
+
+// next reports whether label, the next label in the database, is selected.
+// A nil selector selects everything.
+func (self *selector) next(label Label) bool {
+	if self == nil {
+		return true
+	}
+	selected := false
+	for i, r := range self.sel.Ranges {
+		if self.ranges[i] == rangeBefore && (r.First == "" || r.First == label) {
+			self.ranges[i] = rangeInside
+		}
+		if self.ranges[i] == rangeInside {
+			selected = true
+			if r.Last == label {
+				self.ranges[i] = rangeAfter
+			}
+		}
+	}
+	if selected {
+		return true
+	}
+	if _, ok := self.labels[label]; ok {
+		return true
+	}
+	for _, glob := range self.sel.Globs {
+		if ok, _ := path.Match(glob, string(label)); ok {
+			return true
+		}
+	}
+	for _, re := range self.sel.Patterns {
+		if re.MatchString(string(label)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProofLabels lists the labels that a normal or compressed proof refers
+// to, each once, in the order they first appear.
+func ProofLabels(proof []string) []Label {
+	if len(proof) > 0 && proof[0] == "(" {
+		end, err := FindEndOfProofBlock(proof)
+		if err != nil {
+			return nil
+		}
+		proof = proof[1:end]
+	}
+	seen := map[string]TUnit{}
+	var out []Label
+	for _, tok := range proof {
+		if _, ok := seen[tok]; ok || tok == "?" {
+			continue
+		}
+		seen[tok] = Unit
+		out = append(out, Label(tok))
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// Every proof but ok.1 and ok.2 is wrong, so only unselected ones pass.
+const selectionDatabase = `$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+ok.1 $p wff ph $= wph $.
+ax $a wff ph $.
+bad.1 $p wff ph $= wph wph $.
+ok.2 $p wff ph $= ax $.
+bad.2 $p wff ph $= ax ax $.
+`
+
+func TestSelection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sel  Selection
+		want []Label
+	}{
+		{"everything", Selection{}, []Label{"ok.1", "bad.1", "ok.2", "bad.2"}},
+		{"range", Selection{Ranges: []LabelRange{{First: "ok.1", Last: "ax"}}}, []Label{"ok.1"}},
+		{"open ranges", Selection{Ranges: []LabelRange{{Last: "ok.1"}, {First: "ok.2"}}}, []Label{"ok.1", "ok.2", "bad.2"}},
+		{"glob", Selection{Globs: []string{"ok.*"}}, []Label{"ok.1", "ok.2"}},
+		{"regexp", Selection{Patterns: []*regexp.Regexp{regexp.MustCompile(`\.2$`)}}, []Label{"ok.2", "bad.2"}},
+		{"labels", Selection{Labels: []Label{"ok.2"}}, []Label{"ok.2"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mm := NewMMWithOptions(Options{Selection: tt.sel, Recover: true})
+			_ = mm.CheckString(selectionDatabase)
+			var checked []Label
+			for _, result := range mm.Results {
+				if result.Checked {
+					checked = append(checked, result.Label)
+				}
+				if result.Checked != result.Selected {
+					t.Errorf("%s: checked %v but selected %v", result.Label, result.Checked, result.Selected)
+				}
+			}
+			if !reflect.DeepEqual(checked, tt.want) {
+				t.Errorf("checked %v, want %v", checked, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelection_Validate(t *testing.T) {
+	t.Parallel()
+
+	if err := (Selection{Globs: []string{"ax-["}}).Validate(); err == nil {
+		t.Error("expected an error for a malformed glob")
+	}
+}
+
+func TestProofLabels(t *testing.T) {
+	t.Parallel()
+
+	got := ProofLabels([]string{"(", "wph", "ax", ")", "ABC"})
+	if want := []Label{"wph", "ax"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("compressed: got %v, want %v", got, want)
+	}
+	got = ProofLabels([]string{"wph", "?", "wph", "ax"})
+	if want := []Label{"wph", "ax"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normal: got %v, want %v", got, want)
+	}
+}
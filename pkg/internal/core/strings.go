@@ -11,7 +11,7 @@ func ToTokens(message string) [][]string {
 	return out
 }
 
-func reverse(slice []string) {
+func reverse[T any](slice []T) {
 	last := -1 + len(slice)
 	halfLen := len(slice) / 2
 	for i := 0; i < halfLen; i++ {
@@ -0,0 +1,342 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// Kinds of name that NotFoundError reports.
+const (
+	KindLabel    = "label"
+	KindSymbol   = "symbol"
+	KindVariable = "variable"
+	KindConstant = "constant"
+)
+
+// maxSuggestions is how many similar names a NotFoundError offers.
+const maxSuggestions = 3
+
+// NotFoundError is returned when a proof uses an unknown label, or a
+// statement uses a symbol or variable that is not active.
+type NotFoundError struct {
+	// Kind is KindLabel, KindSymbol, KindVariable or KindConstant.
+	Kind string
+	Name string
+	// Similar names that could be used instead, best first.
+	Suggestions []string
+	// Elsewhere says what Name is, if it exists but cannot be used here,
+	// for example "a constant".
+	Elsewhere string
+	err       error
+}
+
+func (n NotFoundError) Error() string {
+	msg := n.err.Error()
+	if n.Elsewhere != "" {
+		msg += fmt.Sprintf(" (%q is %s)", n.Name, n.Elsewhere)
+	}
+	switch len(n.Suggestions) {
+	case 0:
+	case 1:
+		msg += fmt.Sprintf("; did you mean %q?", n.Suggestions[0])
+	default:
+		quoted := make([]string, len(n.Suggestions))
+		for i, s := range n.Suggestions {
+			quoted[i] = fmt.Sprintf("%q", s)
+		}
+		msg += fmt.Sprintf("; did you mean one of %s?", strings.Join(quoted, ", "))
+	}
+	return msg
+}
+
+func (n NotFoundError) Unwrap() error {
+	return n.err
+}
+
+func AsNotFoundError(e error) *NotFoundError {
+	var n NotFoundError
+	if errors.As(e, &n) {
+		return &n
+	}
+	return nil
+}
+
+// notFound builds a NotFoundError for name, found at pos if that is known,
+// with suggestions taken from candidates.
+func notFound(kind string, name string, candidates []string, pos Pos, err error) NotFoundError {
+	code := mmerror.UndeclaredSymbol
+	if kind == KindLabel {
+		code = mmerror.UnknownLabel
+	}
+	return NotFoundError{
+		Kind:        kind,
+		Name:        name,
+		Suggestions: suggest(name, candidates),
+		err:         MMError{Code: code, err: err, Pos: pos},
+	}
+}
+
+// suggest returns the candidates most like name: those within a small edit
+// distance, and those that differ only in case or in the separators that
+// label names use, such as ax-mp for ax.mp.
+func suggest(name string, candidates []string) []string {
+	type scored struct {
+		name  string
+		score int
+	}
+	limit := len(name) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	var found []scored
+	seen := map[string]TUnit{}
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = Unit
+		if normalizeName(c) == normalizeName(name) {
+			found = append(found, scored{c, 0})
+			continue
+		}
+		if abs(len(c)-len(name)) > limit {
+			continue
+		}
+		if d := editDistance(name, c); d <= limit {
+			found = append(found, scored{c, d})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].score != found[j].score {
+			return found[i].score < found[j].score
+		}
+		return found[i].name < found[j].name
+	})
+	var out []string
+	for i := 0; i < len(found) && i < maxSuggestions; i++ {
+		out = append(out, found[i].name)
+	}
+	return out
+}
+
+// normalizeName folds case and drops the separators used in label names.
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '.', '_':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// editDistance is the number of single-byte insertions, deletions,
+// substitutions and swaps of adjacent bytes that turn a into b.
+func editDistance(a string, b string) int {
+	// Rows i-2, i-1 and i of the table of distances between prefixes.
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// labelCandidates lists the labels a proof may use here: every assertion
+// and the active hypotheses.
+func (self *MM) labelCandidates() []string {
+	var out []string
+	for label, stmt := range self.Labels {
+		if IsAssertion(*stmt) {
+			out = append(out, string(label))
+		}
+	}
+	self.FS.Foreach(func(frame *Frame) int8 {
+		for _, label := range frame.FLabels {
+			out = append(out, string(label))
+		}
+		for _, label := range frame.ELabels {
+			out = append(out, string(label))
+		}
+		return GO
+	})
+	return out
+}
+
+// activeVariables lists the variables declared in the active scopes.
+func (self *MM) activeVariables() []string {
+	var out []string
+	self.FS.Foreach(func(frame *Frame) int8 {
+		for v := range frame.V {
+			out = append(out, v)
+		}
+		return GO
+	})
+	return out
+}
+
+// symbolCandidates lists the constants and active variables.
+func (self *MM) symbolCandidates() []string {
+	out := self.activeVariables()
+	for c := range self.Constants {
+		out = append(out, c)
+	}
+	return out
+}
+
+// unknownLabel explains a label used in a proof that is not available.
+func (self *MM) unknownLabel(label Label) error {
+	return notFound(KindLabel, string(label), self.labelCandidates(), Pos{}, fmt.Errorf("no statement information found for label %q", label))
+}
+
+// unknownSymbol explains a token at pos in a statement that is neither a
+// constant nor an active variable.
+func (self *MM) unknownSymbol(tok string, pos Pos) error {
+	n := notFound(KindSymbol, tok, self.symbolCandidates(), pos, fmt.Errorf("Token %q is not an active symbol", tok))
+	n.Elsewhere = self.whereIs(tok)
+	return n
+}
+
+// unknownVariable explains a $f statement for a variable that is not active.
+func (self *MM) unknownVariable(va string) error {
+	n := notFound(KindVariable, va, self.activeVariables(), Pos{}, fmt.Errorf("var in $f not declared: %q", va))
+	n.Elsewhere = self.whereIs(va)
+	return n
+}
+
+// unknownConstant explains a $f statement whose typecode is not a constant.
+func (self *MM) unknownConstant(typecode string) error {
+	candidates := make([]string, 0, len(self.Constants))
+	for c := range self.Constants {
+		candidates = append(candidates, c)
+	}
+	n := notFound(KindConstant, typecode, candidates, Pos{}, fmt.Errorf("typecode in $f not declared: %q", typecode))
+	n.Elsewhere = self.whereIs(typecode)
+	return n
+}
+
+// whereIs describes a name that exists but is not an active symbol.
+func (self *MM) whereIs(name string) string {
+	for _, v := range self.VariableList {
+		if v == name {
+			return "a variable whose scope has ended"
+		}
+	}
+	if _, ok := self.Constants[name]; ok {
+		return "a constant"
+	}
+	if pos, ok := self.labelPos[Label(name)]; ok {
+		return fmt.Sprintf("a label, defined at %s", pos)
+	}
+	return ""
+}
+
+// explainLater adds a note to each error about a label that is defined
+// further on in the file. It uses the labels read since, if reading carried
+// on, or else scans the rest of toks. It returns err, annotated if need be.
+func (self *MM) explainLater(err error, toks *Toks) error {
+	errs := self.Errors
+	if len(errs) == 0 {
+		errs = []error{err}
+	}
+	wanted := map[Label]TUnit{}
+	for _, e := range errs {
+		if label, ok := unknownLabelIn(e); ok {
+			if _, read := self.labelPos[label]; !read {
+				wanted[label] = Unit
+			}
+		}
+	}
+	later := map[Label]Pos{}
+	if len(wanted) > 0 && len(self.Errors) == 0 {
+		self.scanLabels(toks, wanted, later)
+	}
+	for i, e := range errs {
+		label, ok := unknownLabelIn(e)
+		if !ok {
+			continue
+		}
+		pos, ok := later[label]
+		if !ok {
+			pos, ok = self.labelPos[label]
+		}
+		var located MMError
+		if ok && errors.As(e, &located) && located.located {
+			located.note = fmt.Sprintf("%q is defined later, at %s", label, pos)
+			errs[i] = located
+		}
+	}
+	if len(self.Errors) == 0 {
+		return errs[0]
+	}
+	return err
+}
+
+// unknownLabelIn returns the label of the NotFoundError for a label in err.
+func unknownLabelIn(err error) (Label, bool) {
+	n := AsNotFoundError(err)
+	if n == nil || n.Kind != KindLabel {
+		return "", false
+	}
+	return Label(n.Name), true
+}
+
+// scanLabels reads the rest of toks, without checking anything, and
+// records in found where each wanted label is defined. It reads raw tokens
+// and skips comments itself, so that after an error no $[ $] file is
+// opened and no comment or include reaches the Visitor.
+func (self *MM) scanLabels(toks *Toks, wanted map[Label]TUnit, found map[Label]Pos) {
+	var prev string
+	var prevPos Pos
+	inComment := false
+	for len(wanted) > 0 {
+		if self.checkCtx() != nil {
+			return
+		}
+		tok, err := toks.Read()
+		if err != nil || tok == "" {
+			return
+		}
+		if inComment || tok == "$(" {
+			inComment = tok != "$)"
+			continue
+		}
+		switch tok {
+		case "$a", "$p", "$e", "$f":
+			if _, ok := wanted[Label(prev)]; ok {
+				found[Label(prev)] = prevPos
+				delete(wanted, Label(prev))
+			}
+		}
+		prev, prevPos = tok, toks.Pos()
+	}
+}
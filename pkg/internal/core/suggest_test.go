@@ -0,0 +1,162 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	t.Parallel()
+
+	candidates := []string{"ax-mp", "ax-1", "ax-2", "syl", "syl5", "mpd", "idi"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "ax.mp", want: []string{"ax-mp"}},
+		{name: "AX-MP", want: []string{"ax-mp"}},
+		{name: "sly", want: []string{"syl"}},
+		{name: "ax-3", want: []string{"ax-1", "ax-2"}},
+		{name: "frobnicate", want: nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := suggest(tt.name, candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("suggest(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+
+	if d := editDistance("kitten", "sitting"); d != 3 {
+		t.Errorf("got %d, want 3", d)
+	}
+	if d := editDistance("", "abc"); d != 3 {
+		t.Errorf("got %d, want 3", d)
+	}
+	if d := editDistance("sly", "syl"); d != 1 {
+		t.Errorf("got %d, want 1 for a swap", d)
+	}
+}
+
+const suggestDatabase = `$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+${
+  $v ps $.
+  wps $f wff ps $.
+$}
+`
+
+func TestNotFound(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		extra string
+		want  []string
+	}{
+		{
+			name:  "symbol",
+			extra: "ax1 $a |- pf $.",
+			want:  []string{`Token "pf" is not an active symbol`, `did you mean "ph"?`},
+		},
+		{
+			name:  "variable out of scope",
+			extra: "ax1 $a |- ps $.",
+			want:  []string{`"ps" is a variable whose scope has ended`},
+		},
+		{
+			name:  "label",
+			extra: "ax-1 $a wff ph $.\nth1 $p wff ph $= ax.1 $.",
+			want:  []string{`label "ax.1"`, `did you mean "ax-1"?`},
+		},
+		{
+			name:  "label defined later",
+			extra: "th1 $p wff ph $= th2 $.\nth2 $p wff ph $= wph $.",
+			want:  []string{`"th2" is defined later, at 9:1`},
+		},
+		{
+			name:  "typecode",
+			extra: "wch $f wf ph $.",
+			want:  []string{`did you mean "wff"?`},
+		},
+		{
+			name:  "hypothesis out of scope",
+			extra: "th1 $p wff ps $= wps $.",
+			want:  []string{`Token "ps" is not an active symbol`},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := NewMM(nil).CheckString(suggestDatabase + tt.extra + "\n")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("%q does not contain %q", err, want)
+				}
+			}
+			if AsNotFoundError(err) == nil {
+				t.Errorf("expected a NotFoundError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNotFound_Recover(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMMWithOptions(Options{Recover: true})
+	err := mm.CheckString(suggestDatabase + "th1 $p wff ph $= th2 $.\nth2 $p wff ph $= wph $.\n")
+	if err == nil || !strings.Contains(err.Error(), `"th2" is defined later, at 9:1`) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// scanVisitor records the comments and includes it is told about.
+type scanVisitor struct {
+	NopVisitor
+	comments [][]string
+	includes []string
+}
+
+func (v *scanVisitor) OnComment(text []string) error {
+	v.comments = append(v.comments, text)
+	return nil
+}
+
+func (v *scanVisitor) OnInclude(path string) error {
+	v.includes = append(v.includes, path)
+	return nil
+}
+
+func TestNotFound_ScanAfterError(t *testing.T) {
+	t.Parallel()
+
+	v := &scanVisitor{}
+	mm := NewMMWithOptions(Options{Visitor: v})
+	err := mm.CheckString(suggestDatabase + "th1 $p wff ph $= th2 $.\n$( th3 $p $)\n$[ missing.mm $]\nth2 $p wff ph $= wph $.\n")
+	if err == nil || !strings.Contains(err.Error(), `"th2" is defined later, at 11:1`) {
+		t.Errorf("unexpected error %v", err)
+	}
+	for _, text := range v.comments {
+		if strings.Contains(strings.Join(text, " "), "th3") {
+			t.Errorf("comment after the error reached the visitor: %v", text)
+		}
+	}
+	if len(v.includes) != 0 {
+		t.Errorf("include after the error reached the visitor: %v", v.includes)
+	}
+}
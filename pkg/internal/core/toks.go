@@ -1,16 +1,48 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
 )
 
 type Toks struct {
-	FilesBuf      []*ScanCloser
-	TokBuf        []string
+	FilesBuf []*ScanCloser
+	// Tokens left on the current line, last token first.
+	TokBuf        []Token
 	ImportedFiles map[string]TUnit
+	// Directories searched, in order, for a $[ $] file that cannot be
+	// found relative to the working directory.
+	IncludePath []string
+	// Optional hooks called with the tokens of each comment and the
+	// absolute path of each newly included file.
+	OnComment func(text []string) error
+	OnInclude func(path string) error
+	// May be nil, in which case nothing is logged.
+	Log *slog.Logger
+	// Maximum number of included files, of files open at once, of
+	// tokens read and of bytes in a line of a file; zero means unlimited.
+	MaxIncludes     int
+	MaxIncludeDepth int
+	MaxTokens       int
+	MaxLineLength   int
+	DisableIncludes bool
+	// Bytes consumed so far, and the total size of every file opened so
+	// far, across all included files.
+	BytesRead  int64
+	BytesTotal int64
+	// Position of the token most recently read.
+	pos Pos
+	// The token most recently returned by Readc.
+	last string
+	// Tokens read so far.
+	tokens int
 }
 
 func NewToks(path string, tokens [][]string) (*Toks, error) {
@@ -18,13 +50,41 @@ func NewToks(path string, tokens [][]string) (*Toks, error) {
 	if err != nil {
 		return nil, fmt.Errorf("NewToks: %w", err)
 	}
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+	return newToks(path, scanCloser), nil
+}
+
+// NewStringToks reads a database held in memory. Unlike NewToks with
+// ToTokens, the tokens keep their real columns.
+func NewStringToks(content string) *Toks {
+	return newToks("", NewStringScanCloser(content))
+}
+
+func newToks(path string, scanCloser *ScanCloser) *Toks {
 	return &Toks{
 		FilesBuf: []*ScanCloser{scanCloser},
 		TokBuf:   nil,
 		ImportedFiles: map[string]TUnit{
 			path: Unit,
 		},
-	}, nil
+		BytesTotal: scanCloser.size,
+	}
+}
+
+// depth is the number of included files being read, one inside the other.
+func (self *Toks) depth() int {
+	n := 0
+	for _, f := range self.FilesBuf[1:] {
+		// The leftovers of a line put back by Readf are not counted.
+		if f.counted {
+			n++
+		}
+	}
+	return n
 }
 
 func (self *Toks) getLastFile() *ScanCloser {
@@ -36,26 +96,62 @@ func (self *Toks) getLastFile() *ScanCloser {
 
 func (self *Toks) popFile() error {
 	if len(self.FilesBuf) == 0 {
-		return MMError{errors.New("out of files")}
+		return MMError{err: errors.New("out of files")}
 	}
 	self.FilesBuf[-1+len(self.FilesBuf)].MustClose()
 	self.FilesBuf = self.FilesBuf[:-1+len(self.FilesBuf)]
 	return nil
 }
 
+// trace logs a token at LevelTokens.
+func (self *Toks) trace(msg string, tok string) {
+	if self.Log == nil || !self.Log.Enabled(context.Background(), LevelTokens) {
+		return
+	}
+	self.Log.Log(
+		context.Background(),
+		LevelTokens,
+		msg,
+		slog.String("token", tok),
+		slog.String("file", self.pos.File),
+		slog.Int("line", self.pos.Line),
+		slog.Int("col", self.pos.Col),
+	)
+}
+
+// Pos returns the position of the token most recently read.
+func (self *Toks) Pos() Pos {
+	return self.pos
+}
+
+// Last returns the token most recently returned by Readc.
+func (self *Toks) Last() string {
+	return self.last
+}
+
 func (self *Toks) Read() (string, error) {
 	// Fill the token buffer if it is not already full.
 	for len(self.TokBuf) == 0 {
 		lastFile := self.getLastFile()
 		if lastFile == nil {
-			return "", MMError{errors.New("Unclosed ${ ... $} block at end of file")}
+			return "", MMError{Code: mmerror.UnclosedBlock, err: errors.New("Unclosed ${ ... $} block at end of file")}
 		}
 
-		line := lastFile.Text()
-		if line.Just {
-			self.TokBuf = line.Data
+		lastFile.maxLineLength = self.MaxLineLength
+		line, ok := lastFile.Line()
+		if ok {
+			self.TokBuf = line
+			self.BytesRead += lastFile.lastLen
 			reverse(self.TokBuf)
 		} else {
+			if err := lastFile.Err(); err != nil {
+				if errors.Is(err, mmerror.LimitExceeded) {
+					// The line was skipped; the file can still be read.
+					self.BytesRead += lastFile.lastLen
+					return "", err
+				}
+				return "", fmt.Errorf("reading %q: %w", lastFile.path, err)
+			}
 			err := self.popFile()
 			if err != nil {
 				return "", fmt.Errorf("popping file: %w", err)
@@ -66,10 +162,15 @@ func (self *Toks) Read() (string, error) {
 		}
 	}
 
+	if self.MaxTokens > 0 && self.tokens >= self.MaxTokens {
+		return "", MMError{Code: mmerror.LimitExceeded, err: fmt.Errorf("%w of %d", errTokenLimit, self.MaxTokens), Pos: self.pos}
+	}
+	self.tokens++
 	tok := self.TokBuf[-1+len(self.TokBuf)]
 	self.TokBuf = self.TokBuf[:-1+len(self.TokBuf)]
-	Vprint(90, "Token:", tok)
-	return tok, nil
+	self.pos = tok.Pos
+	self.trace("token", tok.Text)
+	return tok.Text, nil
 }
 
 func (self *Toks) Readf() (string, error) {
@@ -78,19 +179,26 @@ func (self *Toks) Readf() (string, error) {
 		return "", fmt.Errorf("readf: %w", err)
 	}
 	for tok == "$[" {
+		at := self.pos
+		if self.DisableIncludes {
+			return "", MMError{Code: mmerror.IncludeFailed, err: errors.New("$[ $] file inclusion is disabled"), Pos: at}
+		}
 		filename, err := self.Read()
 		if err != nil {
 			return "", fmt.Errorf("reading from file: %w", err)
 		}
 
 		endbracket, err := self.Read()
-		if endbracket != "$]" {
+		if err != nil {
 			return "", fmt.Errorf("reading endbracket: %w", err)
 		}
+		if endbracket != "$]" {
+			return "", MMError{Code: mmerror.UnexpectedToken, err: fmt.Errorf("expected $] after included file name but got %q", endbracket), Pos: self.pos}
+		}
 
-		filename, aErr := filepath.Abs(filename)
-		if aErr != nil {
-			return "", fmt.Errorf("resolving file: %w", err)
+		filename, err = self.resolveInclude(filename)
+		if err != nil {
+			return "", MMError{Code: mmerror.IncludeFailed, err: fmt.Errorf("resolving file: %w", err), Pos: at}
 		}
 
 		_, alreadySeen := self.ImportedFiles[filename]
@@ -99,62 +207,128 @@ func (self *Toks) Readf() (string, error) {
 		} else {
 			// Put the current line back on the stack of files
 			// as a fake file.
-			reversedTokBufs := self.TokBuf[:]
-			reverse(reversedTokBufs)
-			scanCloser, err := NewScanCloser("", [][]string{reversedTokBufs})
-			if err != nil {
-				return "", fmt.Errorf("making scancloser from tokbufs: %w", err)
+			if len(self.TokBuf) != 0 {
+				reversedTokBufs := self.TokBuf[:]
+				reverse(reversedTokBufs)
+				scanCloser := newMemoryScanCloser(reversedTokBufs)
+				self.FilesBuf = append(
+					self.FilesBuf,
+					scanCloser,
+				)
+				self.TokBuf = nil
+			}
+			if self.MaxIncludes > 0 && len(self.ImportedFiles) > self.MaxIncludes {
+				return "", MMError{Code: mmerror.LimitExceeded, err: fmt.Errorf("including %q exceeds the limit of %d included files", filename, self.MaxIncludes), Pos: at}
+			}
+			if self.MaxIncludeDepth > 0 && self.depth() >= self.MaxIncludeDepth {
+				return "", MMError{Code: mmerror.LimitExceeded, err: fmt.Errorf("including %q exceeds the limit of %d nested files", filename, self.MaxIncludeDepth), Pos: at}
 			}
-			self.FilesBuf = append(
-				self.FilesBuf,
-				scanCloser,
-			)
-			self.TokBuf = nil
 			// Add the new file
 			// TODO: I need a method for this.
 			newFile, err := NewScanCloser(filename, nil)
 			if err != nil {
-				return "", fmt.Errorf("making scancloser from %q: %w", filename, err)
+				return "", MMError{Code: mmerror.IncludeFailed, err: fmt.Errorf("making scancloser from %q: %w", filename, err), Pos: at}
 			}
 			self.FilesBuf = append(self.FilesBuf, newFile)
 			self.ImportedFiles[filename] = Unit
+			self.BytesTotal += newFile.size
 			// Change from original. Print the absolute path to the thing we imported.
-			Vprint(5, "Importing file:", filename)
+			if self.Log != nil {
+				self.Log.Debug("including file", slog.String("file", filename), slog.String("from", self.pos.File), slog.Int("line", self.pos.Line))
+			}
+			if self.OnInclude != nil {
+				if err := self.OnInclude(filename); err != nil {
+					return "", fmt.Errorf("include hook: %w", err)
+				}
+			}
 		}
 		tok, err = self.Read()
 		if err != nil {
 			return "", fmt.Errorf("reading: %w", err)
 		}
 	}
-	Vprint(80, "Token once included files expanded:", tok)
+	self.trace("token once included files expanded", tok)
 	return tok, nil
 }
 
+// resolveInclude turns the name in a $[ $] statement into an absolute path.
+// Names that do not exist relative to the working directory are looked up
+// in IncludePath. If nothing matches, the working directory wins so that
+// the error message names the file the user asked for.
+func (self *Toks) resolveInclude(filename string) (string, error) {
+	if !filepath.IsAbs(filename) {
+		if _, err := os.Stat(filename); err != nil {
+			for _, dir := range self.IncludePath {
+				candidate := filepath.Join(dir, filename)
+				if _, err := os.Stat(candidate); err == nil {
+					filename = candidate
+					break
+				}
+			}
+		}
+	}
+	out, err := filepath.Abs(filename)
+	if err != nil {
+		return "", IOError{err}
+	}
+	return out, nil
+}
+
 func (self *Toks) Readc() (string, error) {
 	tok, err := self.Readf()
 	if err != nil {
 		return "", fmt.Errorf("reading: %w", err)
 	}
 	for tok == "$(" {
-		tok, err = self.Read()
+		var text []string
+		// A line of the comment over MaxLineLength is reported once the
+		// comment is closed, so that reading can resume after it.
+		var longLine error
+		read := func() (string, error) {
+			for {
+				tok, err := self.Read()
+				if !errors.Is(err, errLineLimit) {
+					return tok, err
+				}
+				if longLine == nil {
+					longLine = err
+				}
+			}
+		}
+		tok, err = read()
+		if IsEOF(err) {
+			tok, err = "", nil
+		}
 		if err != nil {
 			return "", fmt.Errorf("reading token: %w", err)
 		}
 		for tok != "" && tok != "$)" {
+			text = append(text, tok)
 			// This errors are worse than the original.
 			if strings.Contains(tok, "$(") {
-				return "", MMError{errors.New("token cannot contain $(")}
+				return "", MMError{Code: mmerror.UnexpectedToken, err: errors.New("token cannot contain $(")}
 			}
 			if strings.Contains(tok, "$)") {
-				return "", MMError{errors.New("token cannot contain $)")}
+				return "", MMError{Code: mmerror.UnexpectedToken, err: errors.New("token cannot contain $)")}
+			}
+			tok, err = read()
+			if IsEOF(err) {
+				tok, err = "", nil
 			}
-			tok, err = self.Read()
 			if err != nil {
 				return "", fmt.Errorf("reading token: %w", err)
 			}
 		}
 		if tok != "$)" {
-			panic("internal error: comment not closed")
+			return "", MMError{Code: mmerror.UnclosedComment, err: errors.New("comment not closed at the end of file")}
+		}
+		if self.OnComment != nil {
+			if err := self.OnComment(text); err != nil {
+				return "", fmt.Errorf("comment hook: %w", err)
+			}
+		}
+		if longLine != nil {
+			return "", longLine
 		}
 		tok, err = self.Readf()
 		if err != nil {
@@ -162,6 +336,7 @@ func (self *Toks) Readc() (string, error) {
 			return "", fmt.Errorf("reading token at end of skipping comment: %w", err)
 		}
 	}
-	Vprint(70, "Token once comment skipped:", tok)
+	self.trace("token once comment skipped", tok)
+	self.last = tok
 	return tok, nil
 }
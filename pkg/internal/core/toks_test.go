@@ -1,6 +1,11 @@
 package core
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
 
 func TestToks(t *testing.T) {
 	t.Parallel()
@@ -40,3 +45,34 @@ func TestReadc(t *testing.T) {
 		t.Errorf("bad value of tok: %q", tok)
 	}
 }
+
+func TestReadfIncludePath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "inc.mm"), []byte("b c\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	toks, err := NewToks("", ToTokens("a $[ inc.mm $] d"))
+	if err != nil {
+		t.Fatalf("NewToks failed: %v", err)
+	}
+	toks.IncludePath = []string{dir}
+
+	var got []string
+	for {
+		tok, err := toks.Readf()
+		if IsEOF(err) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("readf failed: %v", err)
+		}
+		got = append(got, tok)
+	}
+
+	if strings.Join(got, " ") != "a b c d" {
+		t.Errorf("unexpected tokens %v", got)
+	}
+}
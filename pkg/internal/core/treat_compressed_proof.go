@@ -3,106 +3,190 @@ package core
 import (
 	"errors"
 	"fmt"
-	"strconv"
+	"log/slog"
 	"strings"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
 )
 
 func FindEndOfProofBlock(proof []string) (int, error) {
 	if len(proof) == 0 {
-		return 0, errors.New("proof string cannot be empty")
+		return 0, MMError{Code: mmerror.MalformedProof, err: errors.New("proof string cannot be empty")}
 	}
 	for i, word := range proof {
 		if word == ")" {
 			return i, nil
 		}
 	}
-	return 0, errors.New(`proof string does not contain ")"`)
+	return 0, MMError{Code: mmerror.MalformedProof, err: errors.New(`proof string does not contain ")"`)}
+}
+
+// The steps of a compressed proof, decoded: a positive number n is the nth
+// mandatory hypothesis, listed label or saved step, and these stand for the
+// letters that are not numbers.
+const (
+	saveStep    = -1 // Z
+	unknownStep = -2 // ?
+)
+
+// decodeCompressedProof turns the letters of a compressed proof into steps.
+// A to T end a number with a digit from 1 to 20, and U to Y are the digits
+// from 1 to 5 that come before it. Z saves the step just taken. A number may
+// refer to one of the labels, counted from 1, or to a step saved before it;
+// anything larger is rejected as soon as it is read, before it can overflow.
+func decodeCompressedProof(letters string, labels int) ([]int, error) {
+	steps := []int{}
+	saved := 0
+	n := 0
+	tooLarge := func(i int) error {
+		return MMError{Code: mmerror.MalformedProof, err: fmt.Errorf(
+			"step %d at letter %d refers to a number larger than the %d labels and %d saved steps",
+			len(steps)+1,
+			i+1,
+			labels,
+			saved,
+		)}
+	}
+	for i, ch := range letters {
+		switch {
+		case 'A' <= ch && ch <= 'T':
+			if n > (labels+saved)/20 {
+				return nil, tooLarge(i)
+			}
+			step := 20*n + int(ch-'A') + 1
+			if step > labels+saved {
+				return nil, tooLarge(i)
+			}
+			steps = append(steps, step)
+			n = 0
+		case 'U' <= ch && ch <= 'Y':
+			// The smallest number this prefix can end in is 20*n + 1.
+			if n > (labels+saved)/100 {
+				return nil, tooLarge(i)
+			}
+			n = 5*n + int(ch-'U') + 1
+		case ch == 'Z':
+			if n != 0 || len(steps) == 0 || steps[len(steps)-1] == saveStep {
+				return nil, MMError{Code: mmerror.MalformedProof, err: fmt.Errorf("Z at letter %d does not directly follow a step", i+1)}
+			}
+			steps = append(steps, saveStep)
+			saved++
+		case ch == '?':
+			if n != 0 {
+				return nil, MMError{Code: mmerror.MalformedProof, err: fmt.Errorf("? at letter %d is in the middle of a number", i+1)}
+			}
+			steps = append(steps, unknownStep)
+		default:
+			return nil, MMError{Code: mmerror.MalformedProof, err: fmt.Errorf("invalid character %q in compressed proof", ch)}
+		}
+	}
+	if n != 0 {
+		return nil, MMError{Code: mmerror.MalformedProof, err: errors.New("compressed proof ends in the middle of a number")}
+	}
+	return steps, nil
+}
+
+// checkLabelList checks the labels between the parentheses of a compressed
+// proof: each one must exist, and be an assertion or an active hypothesis
+// that is not one of hyps, which are numbered without being listed.
+func checkLabelList(mm *MM, hyps []Hyp, labels []string) error {
+	mandatory := map[Label]TUnit{}
+	for _, hyp := range hyps {
+		mandatory[hyp.Label] = Unit
+	}
+	activeHypotheses := mm.FS.ActiveHypotheses()
+	for _, label := range labels {
+		label := Label(label)
+		stmt, ok := mm.Labels[label]
+		if !ok {
+			return mm.unknownLabel(label)
+		}
+		if _, ok := mandatory[label]; ok {
+			return MMError{Code: mmerror.MalformedProof, err: fmt.Errorf("mandatory hypothesis %q may not be listed in a compressed proof", label)}
+		}
+		if IsHypothesis(*stmt) {
+			if _, ok := activeHypotheses[label]; !ok {
+				return mm.inactiveHypothesis(label)
+			}
+		}
+	}
+	return nil
 }
 
-func TreatCompressedProof(mm *MM, fhyps []Fhyp, ehyps []Ehyp, proof []string) (*ProofStack, error) {
-	flabels := []string{}
-	elabels := []string{}
+func TreatCompressedProof(mm *MM, hyps []Hyp, proof []string) (*ProofStack, error) {
 	plabels := []string{}
 	idxBloc, err := FindEndOfProofBlock(proof)
 	if err != nil {
 		return nil, fmt.Errorf("finding end of compressed proof: %w", err)
 	}
-	for _, p := range fhyps {
-		v := mm.FS.LookupF(p.V)
-		if v != nil {
-			return nil, fmt.Errorf("label %q does not exist", p.V)
-		}
-		flabels = append(flabels, string(*v))
+	if err := checkLabelList(mm, hyps, proof[1:idxBloc]); err != nil {
+		return nil, fmt.Errorf("label list: %w", err)
 	}
-	for _, s := range ehyps {
-		v, err := mm.FS.LookupE(Stmt(s))
-		if err != nil {
-			return nil, fmt.Errorf("label %v does not exist: %w", Stmt(s).String(), err)
-		}
-		elabels = append(elabels, string(*v))
+	for _, hyp := range hyps {
+		plabels = append(plabels, string(hyp.Label))
 	}
-	plabels = append(plabels, elabels...)
-	plabels = append(plabels, flabels...)
 	plabels = append(plabels, proof[1:idxBloc]...)
 	compressedProof := strings.Join(proof[idxBloc+1:], "")
-	Vprint(5, "Referenced labels:", fmt.Sprintf("%v", plabels))
 	labelEnd := len(plabels)
-	Vprint(5, "Number of referenced labels:", strconv.Itoa(labelEnd))
-	Vprint(5, "Compressed proof steps:", compressedProof)
-	Vprint(5, "Number of steps", strconv.Itoa(len(compressedProof)))
-	proofInts := []int{}
-	curInt := 0
-	for _, ch := range compressedProof {
-		if ch == 'Z' {
-			proofInts = append(proofInts, -1)
-			continue
-		}
-		if 'A' <= ch || ch <= 'T' {
-			n := 20*curInt + int(ch) - int('A')
-			proofInts = append(proofInts, n)
-			curInt = 0
-			continue
-		}
-		Assert('U' <= ch, "U <= ch")
-		Assert(ch <= 'Y', "ch <= Y")
+	mm.proofLog.Log(
+		mm.logCtx(),
+		slog.LevelDebug,
+		"compressed proof",
+		slog.String("label", string(mm.current)),
+		slog.Any("labels", plabels),
+		slog.Int("label_count", labelEnd),
+		slog.String("steps", compressedProof),
+		slog.Int("step_count", len(compressedProof)),
+	)
+	proofInts, err := decodeCompressedProof(compressedProof, labelEnd)
+	if err != nil {
+		return nil, err
 	}
-	Vprint(5, "Integer-coded steps:", fmt.Sprintf("%v", proofInts))
+	mm.proofLog.Log(mm.logCtx(), slog.LevelDebug, "integer-coded steps", slog.Any("steps", proofInts))
 	stack := NewProofStack()
 	savedStatements := []Stmt{}
-	for _, proofInt := range proofInts {
-		if proofInt == -1 {
+	for step, proofInt := range proofInts {
+		if err := mm.checkCtx(); err != nil {
+			return nil, err
+		}
+		if proofInt == unknownStep {
+			if mm.tracing(mm.proofLog) {
+				mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "unknown step", slog.Int("step", step))
+			}
+			if err := stack.push(mm, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if proofInt == saveStep {
 			stmt := stack.data[-1+len(stack.data)]
-			Vprint(15, "Saving step", stmt.String())
+			if mm.tracing(mm.proofLog) {
+				mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "saving step", slog.Int("step", step), slog.Any("stmt", stmt))
+			}
 			savedStatements = append(savedStatements, stmt)
 			continue
 		}
-		if proofInt < labelEnd {
-			fullStmt, ok := mm.Labels[Label(plabels[proofInt])]
+		if proofInt <= labelEnd {
+			label := plabels[proofInt-1]
+			fullStmt, ok := mm.Labels[Label(label)]
 			if !ok {
-				return nil, errors.New("statement does not exist")
+				return nil, mm.unknownLabel(Label(label))
+			}
+			if mm.tracing(mm.proofLog) {
+				mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "step", slog.Int("step", step), slog.String("label", label), slog.Int("depth", len(stack.data)))
 			}
 			if err := stack.TreatStep(mm, fullStmt); err != nil {
-				return nil, fmt.Errorf("treating step: %w", err)
+				return nil, fmt.Errorf("treating step: %w", atStep(err, step+1, Label(label)))
 			}
 			continue
 		}
-		if proofInt >= labelEnd+len(savedStatements) {
-			return nil, MMError{fmt.Errorf(
-				"Not enough saved proof steps (%d saved but calling %d)",
-				len(savedStatements),
-				proofInt,
-			)}
+		stmt := savedStatements[proofInt-labelEnd-1]
+		if mm.tracing(mm.proofLog) {
+			mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "reusing step", slog.Int("step", step), slog.Any("stmt", stmt))
 		}
-		Assert(labelEnd <= proofInt, "labelEnd <= proofInt")
-		Assert(proofInt <= labelEnd+len(savedStatements), "proofInt <= labelEnd + len(savedStatements)")
-		stmt := savedStatements[proofInt-labelEnd]
-		Vprint(15, "Reusing step", stmt.String())
-		// We already proved this step it can be treated locally as an axiom
-		if err := stack.TreatStep(mm, (&FullStmt{
-			SType: "$a",
-			MStmt: &stmt,
-		}).Check()); err != nil {
-			return nil, fmt.Errorf("pushing local axiom: %w", err)
+		// We already proved this step, so it goes straight onto the stack.
+		if err := stack.push(mm, stmt); err != nil {
+			return nil, fmt.Errorf("reusing step: %w", err)
 		}
 	}
 	return stack, nil
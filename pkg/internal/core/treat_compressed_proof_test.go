@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+func TestDecodeCompressedProof(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		letters string
+		want    []int
+	}{
+		{"", []int{}},
+		{"ABT", []int{1, 2, 20}},
+		{"UA", []int{21}},
+		{"UT", []int{40}},
+		{"VA", []int{41}},
+		{"YT", []int{120}},
+		{"UUA", []int{121}},
+		{"AZBZ", []int{1, saveStep, 2, saveStep}},
+		{"UAZ", []int{21, saveStep}},
+		{"?Z?A", []int{unknownStep, saveStep, unknownStep, 1}},
+	}
+	for _, tt := range tests {
+		got, err := decodeCompressedProof(tt.letters, 200)
+		if err != nil {
+			t.Errorf("%q: %v", tt.letters, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.letters, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeCompressedProof_Errors(t *testing.T) {
+	t.Parallel()
+
+	for _, letters := range []string{"Z", "ZA", "AZZ", "UZA", "U", "AY", "U?A", "a", "A0", "A B"} {
+		if _, err := decodeCompressedProof(letters, 200); !errors.Is(err, mmerror.MalformedProof) {
+			t.Errorf("%q: expected a malformed proof, got %v", letters, err)
+		}
+	}
+}
+
+func TestDecodeCompressedProof_Range(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		letters string
+		labels  int
+		ok      bool
+	}{
+		{"T", 20, true},
+		{"UA", 20, false},
+		{"AZUA", 20, true},
+		{"AZUB", 20, false},
+		{"YT", 120, true},
+		{"UUA", 120, false},
+		// Large enough to overflow an int if the digits were not bounded.
+		{"UUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUA", 1, false},
+	}
+	for _, tt := range tests {
+		_, err := decodeCompressedProof(tt.letters, tt.labels)
+		if tt.ok && err != nil {
+			t.Errorf("%q with %d labels: %v", tt.letters, tt.labels, err)
+		}
+		if !tt.ok && !errors.Is(err, mmerror.MalformedProof) {
+			t.Errorf("%q with %d labels: expected a malformed proof, got %v", tt.letters, tt.labels, err)
+		}
+	}
+}
+
+func TestTreatCompressedProof_Overflow(t *testing.T) {
+	t.Parallel()
+
+	mm := fuzzMM(t)
+	assertion := mm.FS.MakeAssertion(Stmt{"|-", "ps"})
+	proof := []string{"(", "ax-1", ")", "UUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUUA"}
+	if _, err := TreatCompressedProof(mm, assertion.Hyps, proof); !errors.Is(err, mmerror.MalformedProof) {
+		t.Errorf("expected a malformed proof, got %v", err)
+	}
+}
@@ -1,41 +1,77 @@
 package core
 
-import "fmt"
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
 
 func TreatNormalProof(mm *MM, proof []string) (*ProofStack, error) {
 	stack := NewProofStack()
-	activeHypotheses := map[Label]TUnit{}
+	activeHypotheses := mm.FS.ActiveHypotheses()
 
-	mm.FS.Foreach(func(frame *Frame) int8 {
-		for _, label := range frame.FLabels {
-			activeHypotheses[label] = Unit
-		}
-		for _, label := range frame.ELabels {
-			activeHypotheses[label] = Unit
+	for step, label := range proof {
+		if err := mm.checkCtx(); err != nil {
+			return nil, err
 		}
-		return GO
-	})
-
-	for _, label := range proof {
 		label := Label(label)
+		if mm.tracing(mm.proofLog) {
+			mm.proofLog.LogAttrs(mm.logCtx(), LevelTrace, "step", slog.Int("step", step), slog.String("label", string(label)), slog.Int("depth", len(stack.data)))
+		}
+		if label == "?" {
+			if err := stack.push(mm, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		stmtInfo, ok := mm.Labels[label]
 		if !ok {
-			return nil, MMError{fmt.Errorf("no statement information found for label %q", label)}
+			return nil, mm.unknownLabel(label)
 		}
 		labelType := stmtInfo.SType
 		if labelType == "$e" || labelType == "$f" {
 			if _, ok := activeHypotheses[label]; ok {
 				if err := stack.TreatStep(mm, stmtInfo); err != nil {
-					return nil, fmt.Errorf("treating %q step: %w", labelType, err)
+					return nil, fmt.Errorf("treating %q step: %w", labelType, atStep(err, step+1, label))
 				}
 			} else {
-				return nil, MMError{fmt.Errorf("the label %q is the label of a nonactive hypothesis", label)}
+				return nil, mm.inactiveHypothesis(label)
 			}
 		} else {
 			if err := stack.TreatStep(mm, stmtInfo); err != nil {
-				return nil, fmt.Errorf("treating non-{$e,$f} %q step: %w", labelType, err)
+				return nil, fmt.Errorf("treating non-{$e,$f} %q step: %w", labelType, atStep(err, step+1, label))
 			}
 		}
 	}
 	return stack, nil
 }
+
+// inactiveHypothesis explains a proof step that uses a hypothesis whose scope
+// has ended.
+func (self *MM) inactiveHypothesis(label Label) error {
+	return MMError{Code: mmerror.InactiveHypothesis, err: fmt.Errorf("the label %q is the label of a nonactive hypothesis, defined at %s in a scope that has ended", label, self.labelPos[label])}
+}
+
+// countUnknownSteps returns the number of unknown steps, written ?, in a
+// normal or compressed proof.
+func countUnknownSteps(proof []string) int {
+	n := 0
+	if len(proof) > 0 && proof[0] == "(" {
+		end, err := FindEndOfProofBlock(proof)
+		if err != nil {
+			return 0
+		}
+		for _, word := range proof[end+1:] {
+			n += strings.Count(word, "?")
+		}
+		return n
+	}
+	for _, label := range proof {
+		if label == "?" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,69 @@
+package core
+
+import "errors"
+
+// ErrStop may be returned by a Visitor callback to end Read early without
+// reporting an error, as if EndLabel had been reached.
+var ErrStop = errors.New("stop reading")
+
+// Visitor receives callbacks from MM.Read in source order. Returning a
+// non-nil error from any callback aborts the read, and Read returns that
+// error wrapped, except for ErrStop.
+//
+// Embed NopVisitor to implement only the callbacks you need.
+type Visitor interface {
+	OnConstant(tok string) error
+	OnVariable(tok string) error
+	// stype is "$e" or "$f".
+	OnHypothesis(label Label, stype string, stmt Stmt) error
+	OnAxiom(label Label, assertion *Assertion) error
+	// result.Err holds the verification error, if any. OnTheorem is
+	// called for invalid proofs before Read returns the error.
+	OnTheorem(label Label, assertion *Assertion, proof []string, result Result) error
+	OnDisjoint(vars []string) error
+	OnScopeOpen() error
+	OnScopeClose() error
+	// text is the tokens between $( and $).
+	OnComment(text []string) error
+	// path is the absolute path of a newly included file.
+	OnInclude(path string) error
+}
+
+// NopVisitor implements every Visitor callback by doing nothing.
+type NopVisitor struct{}
+
+func (NopVisitor) OnConstant(string) error                             { return nil }
+func (NopVisitor) OnVariable(string) error                             { return nil }
+func (NopVisitor) OnHypothesis(Label, string, Stmt) error              { return nil }
+func (NopVisitor) OnAxiom(Label, *Assertion) error                     { return nil }
+func (NopVisitor) OnTheorem(Label, *Assertion, []string, Result) error { return nil }
+func (NopVisitor) OnDisjoint([]string) error                           { return nil }
+func (NopVisitor) OnScopeOpen() error                                  { return nil }
+func (NopVisitor) OnScopeClose() error                                 { return nil }
+func (NopVisitor) OnComment([]string) error                            { return nil }
+func (NopVisitor) OnInclude(string) error                              { return nil }
+
+// visit runs callback against the visitor, if there is one.
+func (self *MM) visit(callback func(v Visitor) error) error {
+	if self.Visitor == nil {
+		return nil
+	}
+	if err := callback(self.Visitor); err != nil {
+		return visitorError{err}
+	}
+	return nil
+}
+
+// visitorError marks an error returned by the Visitor, which is never
+// recovered from.
+type visitorError struct {
+	err error
+}
+
+func (v visitorError) Error() string {
+	return v.err.Error()
+}
+
+func (v visitorError) Unwrap() error {
+	return v.err
+}
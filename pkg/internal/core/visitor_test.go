@@ -0,0 +1,117 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type recordingVisitor struct {
+	NopVisitor
+	events []string
+	stopAt string
+}
+
+func (r *recordingVisitor) record(event string) error {
+	r.events = append(r.events, event)
+	if event == r.stopAt {
+		return ErrStop
+	}
+	return nil
+}
+
+func (r *recordingVisitor) OnConstant(tok string) error { return r.record("c " + tok) }
+func (r *recordingVisitor) OnVariable(tok string) error { return r.record("v " + tok) }
+func (r *recordingVisitor) OnHypothesis(label Label, stype string, stmt Stmt) error {
+	return r.record(fmt.Sprintf("%s %s %s", stype, label, stmt))
+}
+func (r *recordingVisitor) OnAxiom(label Label, assertion *Assertion) error {
+	return r.record(fmt.Sprintf("$a %s %s", label, assertion.S))
+}
+func (r *recordingVisitor) OnTheorem(label Label, assertion *Assertion, proof []string, result Result) error {
+	return r.record(fmt.Sprintf("$p %s %s checked=%v err=%v", label, strings.Join(proof, " "), result.Checked, result.Err != nil))
+}
+func (r *recordingVisitor) OnDisjoint(vars []string) error {
+	return r.record("$d " + strings.Join(vars, " "))
+}
+func (r *recordingVisitor) OnScopeOpen() error  { return r.record("${") }
+func (r *recordingVisitor) OnScopeClose() error { return r.record("$}") }
+func (r *recordingVisitor) OnComment(text []string) error {
+	return r.record("$( " + strings.Join(text, " "))
+}
+
+const visitorDatabase = `$( header $)
+$c |- wff $.
+$v ph ps $.
+${
+  wph $f wff ph $.
+  $d ph ps $.
+  idi.1 $e |- ph $.
+  idi $p |- ph $= ( ) B $.
+$}
+`
+
+func TestVisitor(t *testing.T) {
+	t.Parallel()
+
+	visitor := &recordingVisitor{}
+	mm := NewMM(nil)
+	mm.Visitor = visitor
+	if err := mm.CheckString(visitorDatabase); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"$( header",
+		"c |-",
+		"c wff",
+		"v ph",
+		"v ps",
+		"${",
+		"$f wph wff ph",
+		"$d ph ps",
+		"$e idi.1 |- ph",
+		"$p idi ( ) B checked=true err=false",
+		"$}",
+	}
+	if strings.Join(visitor.events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected events:\n%s", strings.Join(visitor.events, "\n"))
+	}
+}
+
+func TestVisitor_Stop(t *testing.T) {
+	t.Parallel()
+
+	visitor := &recordingVisitor{stopAt: "v ph"}
+	mm := NewMM(nil)
+	mm.Visitor = visitor
+	if err := mm.CheckString(visitorDatabase); err != nil {
+		t.Fatal(err)
+	}
+	if len(visitor.events) != 4 {
+		t.Errorf("read did not stop: %v", visitor.events)
+	}
+}
+
+type failingVisitor struct {
+	NopVisitor
+}
+
+var errVisitor = errors.New("visitor failed")
+
+func (failingVisitor) OnAxiom(Label, *Assertion) error { return errVisitor }
+
+func TestVisitor_Abort(t *testing.T) {
+	t.Parallel()
+
+	mm := NewMM(nil)
+	mm.Visitor = failingVisitor{}
+	err := mm.CheckString("$c |- $. ax $a |- $. $c x $.")
+	if !errors.Is(err, errVisitor) {
+		t.Fatalf("expected visitor error, got %v", err)
+	}
+	if _, ok := mm.Constants["x"]; ok {
+		t.Error("read continued after visitor error")
+	}
+}
@@ -0,0 +1,186 @@
+package mmchecker
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/internal/core"
+)
+
+// Config controls a single verification run. Runs with different configs may
+// proceed concurrently.
+type Config struct {
+	// Logger receives structured log records from the verifier. Nil discards them.
+	Logger *slog.Logger
+	// LogLevels overrides the minimum level of Logger for individual subsystems,
+	// keyed by SubsystemReader, SubsystemToks or SubsystemProof.
+	LogLevels map[string]slog.Level
+	// BeginLabel, if set, skips proof checking until this label is seen.
+	BeginLabel string
+	// EndLabel, if set, stops reading when this label is seen.
+	EndLabel string
+	// Selection, if not empty, limits proof checking to the theorems it selects.
+	// The rest of the database is still read.
+	Selection Selection
+	// IncludePath lists directories searched for $[ $] files.
+	IncludePath []string
+	// ParseOnly reads the database without checking any proofs.
+	ParseOnly bool
+	// DisableIncludes makes $[ $] statements an error.
+	DisableIncludes bool
+	// Limits bound the work done by the verifier.
+	Limits Limits
+	// ProofTimeout, if positive, is the time allowed to verify any single proof. A
+	// proof that takes longer is reported like an exceeded limit.
+	ProofTimeout time.Duration
+	// Progress, if set, is called as reading proceeds, at most once per
+	// ProgressInterval, and once more with Done set when reading stops.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+	// Visitor, if set, receives a callback for each part of the database as it is read.
+	Visitor Visitor
+	// Recover keeps going after a malformed statement or a failed proof, so that
+	// Report.Errors lists every problem. A failed theorem is still registered.
+	Recover bool
+	// MaxErrors, if positive, stops a recovering run after this many errors.
+	MaxErrors int
+	// AllowIncomplete reports proofs with unknown steps, written ?, as incomplete
+	// without failing the run. Otherwise each one is an error with the code
+	// mmerror.IncompleteProof.
+	AllowIncomplete bool
+}
+
+// Levels below slog.LevelDebug used by the verifier.
+const (
+	// LevelTrace covers individual statements, proof steps and substitutions.
+	LevelTrace = core.LevelTrace
+	// LevelTokens covers every token read.
+	LevelTokens = core.LevelTokens
+)
+
+// Subsystems that log. Each record carries one as its "subsystem" attribute.
+const (
+	SubsystemReader = core.SubsystemReader
+	SubsystemToks   = core.SubsystemToks
+	SubsystemProof  = core.SubsystemProof
+)
+
+// Limits bound the work done by a run, for databases that are not trusted. Zero
+// means unlimited. Going over a limit is an error with the code
+// mmerror.LimitExceeded. With Config.Recover the statement at fault is skipped,
+// except after MaxTokens, which ends the run.
+type Limits struct {
+	// MaxProofLength is the maximum number of labels in a single proof.
+	MaxProofLength int
+	// MaxIncludes is the maximum number of files pulled in by $[ $].
+	MaxIncludes int
+	// MaxIncludeDepth is the maximum number of files including each other.
+	MaxIncludeDepth int
+	// MaxTokens is the maximum number of tokens read, comments included.
+	MaxTokens int
+	// MaxStatementLength is the maximum number of symbols in a statement other
+	// than a proof.
+	MaxStatementLength int
+	// MaxLineLength is the maximum number of bytes in a line of a file, not
+	// counting the newline. A longer line is skipped without being held in memory.
+	MaxLineLength int
+	// MaxStackDepth is the maximum number of entries on the proof stack.
+	MaxStackDepth int
+	// MaxSubstitutionLength is the maximum number of symbols in a statement built
+	// by substitution while checking a proof.
+	MaxSubstitutionLength int
+}
+
+// Progress is a snapshot of how far a run has got.
+type Progress struct {
+	// BytesRead counts bytes consumed across all included files. BytesTotal is the
+	// size of every file opened so far and grows as $[ $] pulls in more files.
+	BytesRead  int64
+	BytesTotal int64
+	// Statements counts statements read, including unlabeled ones such as $c.
+	Statements int
+	// ProofsVerified counts proofs checked, valid or not.
+	ProofsVerified int
+	// Label is the most recently read label.
+	Label string
+	// Done is set on the final call.
+	Done bool
+}
+
+// IsVerificationFailure reports whether err was caused by a proof that does not check,
+// as opposed to a syntax or I/O problem.
+func IsVerificationFailure(err error) bool {
+	return core.AsVerifyError(err) != nil
+}
+
+// CanceledAt reports whether err means the run was stopped by its context, and if
+// so which label was being processed. The label is empty if
+// the run stopped before reading any label.
+func CanceledAt(err error) (string, bool) {
+	cancelErr := core.AsCancelError(err)
+	if cancelErr == nil {
+		return "", false
+	}
+
+	return string(cancelErr.Label), true
+}
+
+// newMM builds a core verifier configured by cfg.
+func newMM(cfg Config) *core.MM {
+	return core.NewMMWithOptions(newOptions(cfg))
+}
+
+// newOptions translates cfg for the core verifier.
+func newOptions(cfg Config) core.Options {
+	opts := core.Options{
+		Logger:    cfg.Logger,
+		LogLevels: cfg.LogLevels,
+		Limits: core.Limits{
+			MaxProofLength:        cfg.Limits.MaxProofLength,
+			MaxIncludes:           cfg.Limits.MaxIncludes,
+			MaxIncludeDepth:       cfg.Limits.MaxIncludeDepth,
+			MaxTokens:             cfg.Limits.MaxTokens,
+			MaxStatementLength:    cfg.Limits.MaxStatementLength,
+			MaxLineLength:         cfg.Limits.MaxLineLength,
+			MaxStackDepth:         cfg.Limits.MaxStackDepth,
+			MaxSubstitutionLength: cfg.Limits.MaxSubstitutionLength,
+		},
+		ProofTimeout:    cfg.ProofTimeout,
+		ParseOnly:       cfg.ParseOnly,
+		DisableIncludes: cfg.DisableIncludes,
+		Recover:         cfg.Recover,
+		MaxErrors:       cfg.MaxErrors,
+		AllowIncomplete: cfg.AllowIncomplete,
+		Selection:       cfg.Selection.core(),
+	}
+
+	if cfg.BeginLabel != "" {
+		label := core.Label(cfg.BeginLabel)
+		opts.BeginLabel = &label
+	}
+
+	if cfg.EndLabel != "" {
+		label := core.Label(cfg.EndLabel)
+		opts.EndLabel = &label
+	}
+
+	if cfg.Progress != nil {
+		opts.ProgressInterval = cfg.ProgressInterval
+		opts.Progress = func(p core.Progress) {
+			cfg.Progress(Progress{
+				BytesRead:      p.BytesRead,
+				BytesTotal:     p.BytesTotal,
+				Statements:     p.Statements,
+				ProofsVerified: p.ProofsVerified,
+				Label:          string(p.Label),
+				Done:           p.Done,
+			})
+		}
+	}
+
+	if cfg.Visitor != nil {
+		opts.Visitor = visitorAdapter{v: cfg.Visitor}
+	}
+
+	return opts
+}
@@ -0,0 +1,112 @@
+package mmchecker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// conformanceDir holds the conformance corpus: one small database per rule of the
+// Metamath specification. Each file starts with a comment such as
+//
+//	$( expect: pass
+//	   What the file checks. $)
+//
+// or "expect: fail CODE", where CODE is the mmerror code it must fail with. Files
+// in its include directory are only read through $[ $] statements.
+const conformanceDir = "testdata/conformance"
+
+// knownFailures lists the corpus files the checker does not conform to yet, and
+// why. A file that starts conforming must be removed from the list.
+var knownFailures = map[string]string{}
+
+// expectation is the outcome a corpus file asks for.
+type expectation struct {
+	pass bool
+	code mmerror.Code
+}
+
+func (e expectation) String() string {
+	if e.pass {
+		return "pass"
+	}
+
+	return "fail " + string(e.code)
+}
+
+var errNoExpectation = errors.New(`file does not start with "$( expect: pass" or "$( expect: fail CODE"`)
+
+func readExpectation(path string) (expectation, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return expectation{}, fmt.Errorf("read expectation: %w", err)
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) < 3 || fields[0] != "$(" || fields[1] != "expect:" {
+		return expectation{}, errNoExpectation
+	}
+
+	switch {
+	case fields[2] == "pass":
+		return expectation{pass: true}, nil
+	case fields[2] == "fail" && len(fields) > 3 && mmerror.Code(fields[3]).Description() != mmerror.Unknown.Description():
+		return expectation{code: mmerror.Code(fields[3])}, nil
+	default:
+		return expectation{}, errNoExpectation
+	}
+}
+
+// TestConformance checks every file of the conformance corpus.
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	paths, err := filepath.Glob(filepath.Join(conformanceDir, "*.mm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) == 0 {
+		t.Fatalf("no files in %s", conformanceDir)
+	}
+
+	for _, path := range paths {
+		path := path
+		name := strings.TrimSuffix(filepath.Base(path), ".mm")
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			want, err := readExpectation(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = ValidateWithConfig(context.Background(), path, "", Config{
+				IncludePath: []string{conformanceDir},
+			})
+
+			got := expectation{pass: err == nil}
+			if err != nil {
+				got.code = mmerror.CodeOf(err)
+			}
+
+			reason, known := knownFailures[name]
+
+			switch {
+			case got == want && known:
+				t.Errorf("conforms now, remove it from knownFailures (%s)", reason)
+			case got != want && known:
+				t.Skipf("known failure: %s", reason)
+			case got != want:
+				t.Errorf("expected %v, got %v: %v", want, got, err)
+			}
+		})
+	}
+}
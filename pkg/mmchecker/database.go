@@ -0,0 +1,187 @@
+package mmchecker
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/internal/core"
+)
+
+// Database is a read-only view of a loaded database.
+//
+// Every method returns fresh slices, so callers may modify what they get back.
+type Database struct {
+	mm     *core.MM
+	report *Report
+	// labels in declaration order, and where each one was declared.
+	labels    []string
+	positions map[string]Position
+}
+
+// Entry describes one labeled statement.
+type Entry struct {
+	Label    string
+	Kind     Kind
+	Symbols  []string
+	Position Position
+}
+
+// Hypothesis is a mandatory hypothesis of an assertion.
+type Hypothesis struct {
+	Label   string
+	Kind    Kind
+	Symbols []string
+}
+
+// DisjointPair is a mandatory $d restriction. First sorts before Second.
+type DisjointPair struct {
+	First  string
+	Second string
+}
+
+func newDatabase(mm *core.MM, report *Report) *Database {
+	db := &Database{
+		mm:        mm,
+		report:    report,
+		positions: map[string]Position{},
+	}
+
+	// A $p whose proof failed is registered like any other statement, so that
+	// a recovering run can still check the proofs that use it. The report
+	// records that it failed.
+	for _, result := range mm.Results {
+		label := string(result.Label)
+		db.labels = append(db.labels, label)
+		db.positions[label] = newPosition(result.Pos)
+	}
+
+	return db
+}
+
+// Report returns the verification report produced while loading.
+func (db *Database) Report() *Report {
+	return db.report
+}
+
+// Labels returns every label in declaration order.
+func (db *Database) Labels() []string {
+	return append([]string(nil), db.labels...)
+}
+
+// Lookup returns the statement with the given label.
+func (db *Database) Lookup(label string) (Entry, bool) {
+	fullStmt, ok := db.mm.Labels[core.Label(label)]
+	if !ok {
+		return Entry{}, false
+	}
+
+	out := Entry{
+		Label:    label,
+		Kind:     Kind(fullStmt.SType),
+		Position: db.positions[label],
+	}
+
+	if fullStmt.MStmt != nil {
+		out.Symbols = append([]string(nil), *fullStmt.MStmt...)
+	} else {
+		out.Symbols = append([]string(nil), fullStmt.MAssertion.S...)
+	}
+
+	return out, true
+}
+
+// Hypotheses returns the mandatory hypotheses of an assertion in the order a proof
+// must supply them.
+func (db *Database) Hypotheses(label string) ([]Hypothesis, error) {
+	assertion, err := db.assertion(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return hypotheses(assertion), nil
+}
+
+// Disjoint returns the mandatory $d pairs of an assertion, sorted.
+func (db *Database) Disjoint(label string) ([]DisjointPair, error) {
+	assertion, err := db.assertion(label)
+	if err != nil {
+		return nil, err
+	}
+
+	return disjointPairs(assertion), nil
+}
+
+// Constants returns every constant in declaration order.
+func (db *Database) Constants() []string {
+	return append([]string(nil), db.mm.ConstantList...)
+}
+
+// Variables returns every variable in order of first declaration. A variable
+// declared again in a later scope is listed once.
+func (db *Database) Variables() []string {
+	seen := map[string]bool{}
+
+	var out []string
+
+	for _, v := range db.mm.VariableList {
+		if !seen[v] {
+			seen[v] = true
+
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// assertion returns the assertion for an $a or $p label.
+func (db *Database) assertion(label string) (*core.Assertion, error) {
+	fullStmt, ok := db.mm.Labels[core.Label(label)]
+	if !ok {
+		return nil, fmt.Errorf("label %q not found", label)
+	}
+
+	if fullStmt.MAssertion == nil {
+		return nil, fmt.Errorf("label %q is a %s hypothesis, not an assertion", label, fullStmt.SType)
+	}
+
+	return fullStmt.MAssertion, nil
+}
+
+// hypotheses converts the mandatory hypotheses of a core assertion.
+func hypotheses(assertion *core.Assertion) []Hypothesis {
+	out := make([]Hypothesis, 0, len(assertion.Hyps))
+
+	for _, hyp := range assertion.Hyps {
+		kind := KindEssential
+		if hyp.F != nil {
+			kind = KindFloating
+		}
+
+		out = append(out, Hypothesis{
+			Label:   string(hyp.Label),
+			Kind:    kind,
+			Symbols: append([]string(nil), hyp.Stmt()...),
+		})
+	}
+
+	return out
+}
+
+// disjointPairs converts the mandatory $d pairs of a core assertion, sorted.
+func disjointPairs(assertion *core.Assertion) []DisjointPair {
+	out := make([]DisjointPair, 0, len(assertion.Dvs))
+	for dv := range assertion.Dvs {
+		out = append(out, DisjointPair{First: dv.First, Second: dv.Second})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].First != out[j].First {
+			return out[i].First < out[j].First
+		}
+
+		return out[i].Second < out[j].Second
+	})
+
+	return out
+}
@@ -0,0 +1,115 @@
+package mmchecker
+
+import (
+	"context"
+	"testing"
+)
+
+// TestLoad tests querying a loaded database.
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	db, err := Load(context.Background(), "", tinyDatabase+`${
+$v ps $.
+wps $f wff ps $.
+$d ph ps $.
+ax2 $a |- ps $.
+$}
+`, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := makeDiff(db.Labels(), []string{"wph", "idi.1", "idi", "ax", "wps", "ax2"}); e != nil {
+		t.Error(e)
+	}
+
+	if e := makeDiff(db.Constants(), []string{"|-", "wff"}); e != nil {
+		t.Error(e)
+	}
+
+	if e := makeDiff(db.Variables(), []string{"ph", "ps"}); e != nil {
+		t.Error(e)
+	}
+
+	entry, ok := db.Lookup("idi")
+	if !ok {
+		t.Fatal("idi not found")
+	}
+
+	if e := makeDiff(entry, Entry{
+		Label:    "idi",
+		Kind:     KindTheorem,
+		Symbols:  []string{"|-", "ph"},
+		Position: Position{Line: 5, Column: 1},
+	}); e != nil {
+		t.Error(e)
+	}
+
+	hyps, err := db.Hypotheses("idi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := makeDiff(hyps, []Hypothesis{
+		{Label: "wph", Kind: KindFloating, Symbols: []string{"wff", "ph"}},
+		{Label: "idi.1", Kind: KindEssential, Symbols: []string{"|-", "ph"}},
+	}); e != nil {
+		t.Error(e)
+	}
+
+	hyps, err = db.Hypotheses("ax2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := makeDiff(hyps, []Hypothesis{
+		{Label: "wph", Kind: KindFloating, Symbols: []string{"wff", "ph"}},
+		{Label: "idi.1", Kind: KindEssential, Symbols: []string{"|-", "ph"}},
+		{Label: "wps", Kind: KindFloating, Symbols: []string{"wff", "ps"}},
+	}); e != nil {
+		t.Error(e)
+	}
+
+	dvs, err := db.Disjoint("ax2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := makeDiff(dvs, []DisjointPair{{First: "ph", Second: "ps"}}); e != nil {
+		t.Error(e)
+	}
+
+	_, err = db.Hypotheses("wph")
+	if e := errContains(err, "not an assertion"); e != nil {
+		t.Error(e)
+	}
+
+	if _, ok := db.Lookup("nope"); ok {
+		t.Error("unexpected label nope")
+	}
+}
+
+// TestLoad_FailedProof tests that a theorem whose proof failed is still in
+// the database.
+func TestLoad_FailedProof(t *testing.T) {
+	t.Parallel()
+
+	db, err := Load(context.Background(), "", tinyDatabase+"bad $p |- ph $= wph $.\nlast $a |- ph $.\n", Config{Recover: true})
+	if !IsVerificationFailure(err) {
+		t.Fatalf("expected a verification failure, got %v", err)
+	}
+
+	if e := makeDiff(db.Labels(), []string{"wph", "idi.1", "idi", "ax", "bad", "last"}); e != nil {
+		t.Error(e)
+	}
+
+	entry, ok := db.Lookup("bad")
+	if !ok {
+		t.Fatal("bad not found")
+	}
+
+	if e := makeDiff(entry.Position, Position{Line: 7, Column: 1}); e != nil {
+		t.Error(e)
+	}
+}
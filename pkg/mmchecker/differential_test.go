@@ -0,0 +1,280 @@
+package mmchecker
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/internal/core"
+)
+
+// The differential tests only compare how the core verifier and the kernel read
+// a database: which statements they accept and which labels they register. The
+// kernel does not check proofs, so the core verifier runs with ParseOnly and the
+// generator writes every proof as "?". Proof checking is covered by the
+// conformance corpus and the mutation tests instead.
+
+// differentialRuns is the number of random databases TestDifferentialParse tries.
+const differentialRuns = 2000
+
+// compareParsers reads content with both the core verifier and the kernel, without
+// checking proofs, and returns an error describing how they disagree, if they do.
+func compareParsers(content string) error {
+	mm := core.NewMMWithOptions(core.Options{ParseOnly: true, DisableIncludes: true})
+	coreErr := mm.CheckString(content)
+
+	k := newKernel()
+	kernelErr := processDatabase(k, parseString(content))
+
+	if (coreErr == nil) != (kernelErr == nil) {
+		return fmt.Errorf("core: %v, kernel: %v", verdict(coreErr), verdict(kernelErr))
+	}
+
+	coreLabels := make([]string, 0, len(mm.Labels))
+	for label := range mm.Labels {
+		coreLabels = append(coreLabels, string(label))
+	}
+
+	kernelLabels := make([]string, 0, len(k.labels))
+	for label := range k.labels {
+		kernelLabels = append(kernelLabels, label)
+	}
+
+	sort.Strings(coreLabels)
+	sort.Strings(kernelLabels)
+
+	if e := makeDiff(kernelLabels, coreLabels); e != nil {
+		return fmt.Errorf("labels (-core +kernel): %w", e)
+	}
+
+	return nil
+}
+
+func verdict(err error) string {
+	if err == nil {
+		return "accepted"
+	}
+
+	return fmt.Sprintf("rejected (%v)", err)
+}
+
+// databaseGenerator writes small random databases. It mostly follows the rules,
+// so that reading gets past the first few statements, and breaks one now and
+// then, for instance by picking a name from the whole pool instead of from what
+// is declared.
+type databaseGenerator struct {
+	r   *rand.Rand
+	out strings.Builder
+	// Declared constants, and the variables declared and typed in each open
+	// scope.
+	constants []string
+	variables [][]string
+	typed     [][]string
+	labels    int
+}
+
+var (
+	constantPool = []string{"|-", "wff", "set", "(", ")", "->"}
+	variablePool = []string{"ph", "ps", "ch", "x", "y", "z"}
+)
+
+func generateDatabase(r *rand.Rand) string {
+	g := &databaseGenerator{r: r, variables: [][]string{nil}, typed: [][]string{nil}}
+
+	g.statement("$c", constantPool[:1+r.Intn(len(constantPool))]...)
+
+	for n := 5 + r.Intn(20); n > 0; n-- {
+		g.step()
+	}
+
+	for len(g.variables) > 1 && !g.fault() {
+		g.closeScope()
+	}
+
+	return g.out.String()
+}
+
+// fault reports whether to break a rule this time.
+func (g *databaseGenerator) fault() bool {
+	return g.r.Intn(50) == 0
+}
+
+// pick returns one of names, or any name from pool if it is time for a fault or
+// names is empty.
+func (g *databaseGenerator) pick(names []string, pool []string) string {
+	if len(names) == 0 || g.fault() {
+		return pool[g.r.Intn(len(pool))]
+	}
+
+	return names[g.r.Intn(len(names))]
+}
+
+func (g *databaseGenerator) active() []string {
+	var out []string
+	for _, names := range g.variables {
+		out = append(out, names...)
+	}
+
+	return out
+}
+
+func (g *databaseGenerator) typedVariables() []string {
+	var out []string
+	for _, names := range g.typed {
+		out = append(out, names...)
+	}
+
+	return out
+}
+
+// without returns the names in pool that are not in names.
+func without(pool []string, names []string) []string {
+	var out []string
+
+	for _, name := range pool {
+		found := false
+
+		for _, other := range names {
+			found = found || name == other
+		}
+
+		if !found {
+			out = append(out, name)
+		}
+	}
+
+	return out
+}
+
+func (g *databaseGenerator) label() string {
+	if g.fault() && g.labels > 0 {
+		return fmt.Sprintf("l%d", g.r.Intn(g.labels))
+	}
+
+	if g.fault() {
+		return g.pick(nil, append(constantPool[:len(constantPool):len(constantPool)], variablePool...))
+	}
+
+	g.labels++
+
+	return fmt.Sprintf("l%d", g.labels-1)
+}
+
+func (g *databaseGenerator) statement(keyword string, body ...string) {
+	if keyword == "$c" {
+		g.constants = append(g.constants, body...)
+	}
+
+	indent := strings.Repeat("  ", len(g.variables)-1)
+	fmt.Fprintf(&g.out, "%s%s %s $.\n", indent, keyword, strings.Join(body, " "))
+}
+
+func (g *databaseGenerator) sentence() []string {
+	out := []string{g.pick(g.constants, constantPool)}
+	for n := g.r.Intn(4); n > 0; n-- {
+		typed := g.typedVariables()
+		if g.r.Intn(2) == 0 && (len(typed) > 0 || g.fault()) {
+			out = append(out, g.pick(typed, variablePool))
+		} else {
+			out = append(out, g.pick(g.constants, constantPool))
+		}
+	}
+
+	return out
+}
+
+func (g *databaseGenerator) closeScope() {
+	g.variables = g.variables[:len(g.variables)-1]
+	g.typed = g.typed[:len(g.typed)-1]
+	g.out.WriteString(strings.Repeat("  ", len(g.variables)-1) + "$}\n")
+}
+
+func (g *databaseGenerator) step() {
+	depth := len(g.variables) - 1
+
+	switch g.r.Intn(10) {
+	case 0:
+		if depth < 3 {
+			g.out.WriteString(strings.Repeat("  ", depth) + "${\n")
+			g.variables = append(g.variables, nil)
+			g.typed = append(g.typed, nil)
+		} else {
+			g.closeScope()
+		}
+	case 1:
+		if depth > 0 {
+			g.closeScope()
+		} else {
+			g.out.WriteString("$( A comment. $)\n")
+		}
+	case 2:
+		name := g.pick(without(variablePool, g.active()), variablePool)
+		g.variables[depth] = append(g.variables[depth], name)
+		g.statement("$v", name)
+	case 3, 4:
+		untyped := without(g.active(), g.typedVariables())
+		if len(untyped) > 0 || g.fault() {
+			va := g.pick(untyped, variablePool)
+			g.typed[depth] = append(g.typed[depth], va)
+			g.statement(g.label()+" $f", g.pick(g.constants, constantPool), va)
+		}
+	case 5:
+		undeclared := without(constantPool, g.constants)
+		if (depth == 0 || g.fault()) && (len(undeclared) > 0 || g.fault()) {
+			g.statement("$c", g.pick(undeclared, constantPool))
+		}
+	case 6:
+		active := g.active()
+		if x, y := g.pick(active, variablePool), g.pick(active, variablePool); x != y || g.fault() {
+			g.statement("$d", x, y)
+		}
+	case 7:
+		g.statement(g.label()+" $e", g.sentence()...)
+	case 8:
+		g.statement(g.label()+" $a", g.sentence()...)
+	default:
+		// Neither side checks the proof.
+		g.statement(g.label()+" $p", append(g.sentence(), "$=", "?")...)
+	}
+}
+
+// TestDifferentialParse checks that the core verifier and the kernel accept the
+// same random databases and register the same labels.
+func TestDifferentialParse(t *testing.T) {
+	t.Parallel()
+
+	accepted := 0
+
+	for seed := int64(0); seed < differentialRuns; seed++ {
+		content := generateDatabase(rand.New(rand.NewSource(seed))) //nolint:gosec // Reproducible, not secret.
+		if err := compareParsers(content); err != nil {
+			t.Fatalf("seed %d: %v\n%s", seed, err, content)
+		}
+
+		if processDatabase(newKernel(), parseString(content)) == nil {
+			accepted++
+		}
+	}
+
+	t.Logf("%d of %d databases accepted", accepted, differentialRuns)
+
+	// A generator that only writes good or only bad databases tests half as much.
+	if accepted == 0 || accepted == differentialRuns {
+		t.Errorf("%d of %d databases accepted", accepted, differentialRuns)
+	}
+}
+
+// FuzzDifferentialParse is TestDifferentialParse for as many seeds as the fuzzer
+// tries.
+func FuzzDifferentialParse(f *testing.F) {
+	f.Add(int64(differentialRuns))
+
+	f.Fuzz(func(t *testing.T, seed int64) {
+		content := generateDatabase(rand.New(rand.NewSource(seed))) //nolint:gosec // Reproducible, not secret.
+		if err := compareParsers(content); err != nil {
+			t.Fatalf("seed %d: %v\n%s", seed, err, content)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package mmchecker
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // available isn't a real type, it marks a symbol as available for use.
@@ -39,6 +40,15 @@ type kernel struct {
 	// Also, note that symbols do NOT contain backreferences to the scope that contains them, so one thing
 	// being alive in a scope does not keep the scope alive.
 	stack []scope
+
+	// Labels stay defined after their scope ends, so they are also kept here, with
+	// the line that defined them.
+	labels map[string]int
+
+	// Variables are also remembered after their scope ends, since their names may
+	// not become constants, with the typecode of their first floating hypothesis
+	// ("" until they have one).
+	variables map[string]string
 }
 
 func newKernel() *kernel {
@@ -76,7 +86,7 @@ func lookup(k *kernel, name string) *symbol {
 }
 
 func lookupType(k *kernel, name string) string {
-	if sym := lookup(k, name); k != nil {
+	if sym := lookup(k, name); sym != nil {
 		return sym.typ
 	}
 
@@ -94,12 +104,22 @@ func normal(k *kernel) {
 	}
 
 	if len(k.stack) == 0 {
-		k.stack = []scope{
-			{
-				symbols:  map[string]*symbol{},
-				distinct: map[*symbol][]*symbol{},
-			},
-		}
+		k.stack = []scope{newScope()}
+	}
+
+	if k.labels == nil {
+		k.labels = map[string]int{}
+	}
+
+	if k.variables == nil {
+		k.variables = map[string]string{}
+	}
+}
+
+func newScope() scope {
+	return scope{
+		symbols:  map[string]*symbol{},
+		distinct: map[*symbol][]*symbol{},
 	}
 }
 
@@ -151,12 +171,29 @@ func readComment(k *kernel, tokens [][]string) ([][]string, error) {
 	return tokens, nil
 }
 
-// processConstant registers a new constant symbol.
+// processConstant registers a new constant symbol. Constants are only declared in
+// the outermost scope.
 func processConstant(k *kernel, linum int, name string) error {
+	if !isMathSymbol(name) {
+		return fmt.Errorf("process constant: %q is not a math symbol", name)
+	}
+
+	if last(k) != 0 {
+		return fmt.Errorf("process constant: %q declared in an inner scope", name)
+	}
+
 	if sym := lookup(k, name); sym != nil {
 		return fmt.Errorf("process constant: symbol %q already exists", name)
 	}
 
+	if _, ok := k.variables[name]; ok {
+		return fmt.Errorf("process constant: symbol %q was declared as a variable", name)
+	}
+
+	if _, ok := k.labels[name]; ok {
+		return fmt.Errorf("process constant: symbol %q is already a label", name)
+	}
+
 	k.stack[last(k)].symbols[name] = &symbol{
 		name:  name,
 		linum: linum,
@@ -168,23 +205,83 @@ func processConstant(k *kernel, linum int, name string) error {
 
 // processVariable registers a new variable symbol.
 func processVariable(k *kernel, linum int, name string) error {
+	if !isMathSymbol(name) {
+		return fmt.Errorf("process variable: %q is not a math symbol", name)
+	}
+
 	if sym := lookup(k, name); sym != nil {
 		return fmt.Errorf("process variable: symbol %q already exists", name)
 	}
 
+	if _, ok := k.labels[name]; ok {
+		return fmt.Errorf("process variable: symbol %q is already a label", name)
+	}
+
+	if _, ok := k.variables[name]; !ok {
+		k.variables[name] = ""
+	}
+
 	k.stack[last(k)].symbols[name] = &symbol{
 		name:  name,
 		linum: linum,
-		typ:   constant,
+		typ:   variable,
+	}
+
+	return nil
+}
+
+// labelChars are the characters labels are made of.
+const labelChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+// isMathSymbol reports whether name is made of printable ASCII characters other
+// than $.
+func isMathSymbol(name string) bool {
+	return name != "" && strings.IndexFunc(name, func(r rune) bool { return r <= ' ' || r > '~' || r == '$' }) < 0
+}
+
+// processLabel checks that name is a new label and records it.
+func processLabel(k *kernel, linum int, name string) error {
+	if strings.IndexFunc(name, func(r rune) bool { return !strings.ContainsRune(labelChars, r) }) >= 0 {
+		return fmt.Errorf("label %q has a character other than letters, digits, '-', '_' and '.'", name)
 	}
 
+	if line, ok := k.labels[name]; ok {
+		return fmt.Errorf("label %q already defined on line %d", name, line)
+	}
+
+	if sym := lookup(k, name); sym != nil {
+		return fmt.Errorf("label %q is already a symbol", name)
+	}
+
+	if _, ok := k.variables[name]; ok {
+		return fmt.Errorf("label %q was declared as a variable", name)
+	}
+
+	k.labels[name] = linum
+
 	return nil
 }
 
 // Also, the leftmost thing must be a constant.
 func processAxiom(k *kernel, linum int, name string, sentence []string) error {
-	if sym := lookup(k, name); sym != nil {
-		return fmt.Errorf("axiom: symbol %q already exists", name)
+	return processSentence(k, linum, name, axiom, sentence)
+}
+
+// processTheorem registers the statement of a theorem. Its proof is not checked.
+func processTheorem(k *kernel, linum int, name string, sentence []string) error {
+	return processSentence(k, linum, name, proof, sentence)
+}
+
+// processEssentialHypothesis processes an essential hypothesis.
+func processEssentialHypothesis(k *kernel, linum int, name string, sentence []string) error {
+	return processSentence(k, linum, name, essential, sentence)
+}
+
+// processSentence registers a labeled sentence of constants and variables, each
+// variable typed by an active floating hypothesis.
+func processSentence(k *kernel, linum int, name string, typ string, sentence []string) error {
+	if len(sentence) == 0 {
+		return fmt.Errorf("%s: %q is empty", typ, name)
 	}
 
 	var symbols []*symbol
@@ -194,65 +291,73 @@ func processAxiom(k *kernel, linum int, name string, sentence []string) error {
 
 		switch {
 		case sym == nil:
-			return fmt.Errorf("axiom: symbol %q in definition of %q does not exist", item, name)
+			return fmt.Errorf("%s: symbol %q in definition of %q does not exist", typ, item, name)
+		case sym.typ == variable && floatingFor(k, sym) == nil:
+			return fmt.Errorf("%s: variable %q in definition of %q has no floating hypothesis", typ, item, name)
 		case sym.typ == constant || sym.typ == variable:
 			symbols = append(symbols, sym)
 		default:
-			return fmt.Errorf("axiom: symbol %q in definition of %q has bad type %q", item, name, sym.typ)
+			return fmt.Errorf("%s: symbol %q in definition of %q has bad type %q", typ, item, name, sym.typ)
 		}
 	}
 
+	if err := processLabel(k, linum, name); err != nil {
+		return fmt.Errorf("%s: %w", typ, err)
+	}
+
 	k.stack[last(k)].symbols[name] = &symbol{
 		name:  name,
 		linum: linum,
-		typ:   axiom,
+		typ:   typ,
 		def:   symbols,
 	}
 
 	return nil
 }
 
-// processFloatingHypothesis processes a floating hypothesis.
-func processFloatingHypothesis(k *kernel, linum int, name string, baseConstant string, baseVariable string) error {
-	if sym := lookup(k, name); sym != nil {
-		return fmt.Errorf("floating: symbol %q already exists", name)
+// floatingFor returns the active floating hypothesis of a variable, or nil.
+func floatingFor(k *kernel, variable *symbol) *symbol {
+	for i := last(k); i >= 0; i-- {
+		for _, sym := range k.stack[i].symbols {
+			if sym.typ == floating && sym.theTerm == variable {
+				return sym
+			}
+		}
 	}
 
-	if typ := lookupType(k, baseConstant); baseConstant != typ {
-		return fmt.Errorf("floating: symbol %q is %q not constant", name, typ)
-	}
+	return nil
+}
 
-	if typ := lookupType(k, baseVariable); baseVariable != typ {
-		return fmt.Errorf("floating: symbol %q is %q not variable", name, typ)
+// processFloatingHypothesis processes a floating hypothesis.
+func processFloatingHypothesis(k *kernel, linum int, name string, baseConstant string, baseVariable string) error {
+	if typ := lookupType(k, baseConstant); typ != constant {
+		return fmt.Errorf("floating: symbol %q is %q not constant", baseConstant, typ)
 	}
 
-	k.stack[last(k)].symbols[name] = &symbol{
-		name:  name,
-		linum: linum,
-		typ:   floating,
+	if typ := lookupType(k, baseVariable); typ != variable {
+		return fmt.Errorf("floating: symbol %q is %q not variable", baseVariable, typ)
 	}
 
-	return nil
-}
-
-// processEssentialHypothesis processes an essential hypothesis.
-func processEssentialHypothesis(k *kernel, linum int, name string, baseConstant string, baseVariable string) error {
-	if sym := lookup(k, name); sym != nil {
-		return fmt.Errorf("essential: symbol %q already exists", name)
+	if other := floatingFor(k, lookup(k, baseVariable)); other != nil {
+		return fmt.Errorf("floating: variable %q already typed by %q", baseVariable, other.name)
 	}
 
-	if typ := lookupType(k, baseConstant); baseConstant != typ {
-		return fmt.Errorf("essential: symbol %q is %q not constant", name, typ)
+	if typ := k.variables[baseVariable]; typ != "" && typ != baseConstant {
+		return fmt.Errorf("floating: variable %q was typed %q before", baseVariable, typ)
 	}
 
-	if typ := lookupType(k, baseVariable); baseVariable != typ {
-		return fmt.Errorf("essential: symbol %q is %q not variable", name, typ)
+	if err := processLabel(k, linum, name); err != nil {
+		return fmt.Errorf("floating: %w", err)
 	}
 
+	k.variables[baseVariable] = baseConstant
+
 	k.stack[last(k)].symbols[name] = &symbol{
-		name:  name,
-		linum: linum,
-		typ:   essential,
+		name:    name,
+		linum:   linum,
+		typ:     floating,
+		theTerm: lookup(k, baseVariable),
+		theType: lookup(k, baseConstant),
 	}
 
 	return nil
@@ -261,11 +366,15 @@ func processEssentialHypothesis(k *kernel, linum int, name string, baseConstant
 // processDisjointnessHypothesis processes a disjointness hypothesis. Wowzers.
 func processDisjointnessHypothesis(k *kernel, linum int, item1 string, item2 string) error {
 	if typ := lookupType(k, item1); typ != variable {
-		return fmt.Errorf("disjoint: symbol %q has type %q not variable", item1, variable)
+		return fmt.Errorf("disjoint: symbol %q has type %q not variable", item1, typ)
 	}
 
 	if typ := lookupType(k, item2); typ != variable {
-		return fmt.Errorf("disjoint: symbol %q has type %q not variable", item2, variable)
+		return fmt.Errorf("disjoint: symbol %q has type %q not variable", item2, typ)
+	}
+
+	if item1 == item2 {
+		return fmt.Errorf("disjoint: variable %q named twice", item1)
 	}
 
 	k.stack[last(k)].distinct[lookup(k, item1)] = append(k.stack[last(k)].distinct[lookup(k, item1)], lookup(k, item2))
@@ -273,3 +382,165 @@ func processDisjointnessHypothesis(k *kernel, linum int, item1 string, item2 str
 
 	return nil
 }
+
+// processDatabase reads a whole database into the kernel, one statement at a time.
+// Proofs are not checked and $[ $] inclusions are not supported.
+func processDatabase(k *kernel, tokens [][]string) error {
+	normal(k)
+
+	words, lines, err := stripComments(tokens)
+	if err != nil {
+		return err
+	}
+
+	label := ""
+
+	for i := 0; i < len(words); i++ {
+		k.linum = lines[i]
+		word := words[i]
+
+		switch word {
+		case "${", "$}":
+			if label != "" {
+				return fmt.Errorf("line %d: label %q before %s", k.linum, label, word)
+			}
+
+			if err := processScope(k, word); err != nil {
+				return fmt.Errorf("line %d: %w", k.linum, err)
+			}
+		case "$c", "$v", "$d", "$f", "$e", "$a", "$p":
+			end := i + 1
+			for end < len(words) && words[end] != "$." {
+				end++
+			}
+
+			if end == len(words) {
+				return fmt.Errorf("line %d: %s statement is not closed", k.linum, word)
+			}
+
+			if err := processStatement(k, label, word, words[i+1:end]); err != nil {
+				return fmt.Errorf("line %d: %w", k.linum, err)
+			}
+
+			label = ""
+			i = end
+		default:
+			if strings.HasPrefix(word, "$") || label != "" {
+				return fmt.Errorf("line %d: unexpected %q", k.linum, word)
+			}
+
+			label = word
+		}
+	}
+
+	if label != "" {
+		return fmt.Errorf("line %d: label %q without a statement", k.linum, label)
+	}
+
+	if last(k) != 0 {
+		return errors.New("${ without $} at the end of the database")
+	}
+
+	return nil
+}
+
+// stripComments flattens tokens into words, leaving out comments, and returns the
+// line of each word.
+func stripComments(tokens [][]string) ([]string, []int, error) {
+	var words []string
+
+	var lines []int
+
+	inComment := false
+
+	for i, row := range tokens {
+		for _, token := range row {
+			switch {
+			case token == "$(" && !inComment:
+				inComment = true
+			case token == "$)" && inComment:
+				inComment = false
+			case strings.Contains(token, "$(") || strings.Contains(token, "$)"):
+				return nil, nil, fmt.Errorf("line %d: unexpected %q", i+1, token)
+			case !inComment:
+				words = append(words, token)
+				lines = append(lines, i+1)
+			}
+		}
+	}
+
+	if inComment {
+		return nil, nil, errors.New("comment not closed at the end of the database")
+	}
+
+	return words, lines, nil
+}
+
+// processScope opens or closes a scope.
+func processScope(k *kernel, word string) error {
+	if word == "${" {
+		k.stack = append(k.stack, newScope())
+
+		return nil
+	}
+
+	if last(k) == 0 {
+		return errors.New("$} without ${")
+	}
+
+	k.stack = k.stack[:last(k)]
+
+	return nil
+}
+
+// processStatement processes the body of one statement, label being empty for the
+// statements that take none.
+func processStatement(k *kernel, label string, keyword string, body []string) error {
+	labeled := keyword == "$f" || keyword == "$e" || keyword == "$a" || keyword == "$p"
+	if labeled != (label != "") {
+		return fmt.Errorf("%s statement with label %q", keyword, label)
+	}
+
+	switch keyword {
+	case "$c":
+		for _, name := range body {
+			if err := processConstant(k, k.linum, name); err != nil {
+				return err
+			}
+		}
+	case "$v":
+		for _, name := range body {
+			if err := processVariable(k, k.linum, name); err != nil {
+				return err
+			}
+		}
+	case "$d":
+		for i := range body {
+			for j := i + 1; j < len(body); j++ {
+				if err := processDisjointnessHypothesis(k, k.linum, body[i], body[j]); err != nil {
+					return err
+				}
+			}
+		}
+	case "$f":
+		if len(body) != 2 {
+			return fmt.Errorf("floating: %q has %d symbols, not 2", label, len(body))
+		}
+
+		return processFloatingHypothesis(k, k.linum, label, body[0], body[1])
+	case "$e":
+		return processEssentialHypothesis(k, k.linum, label, body)
+	case "$a":
+		return processAxiom(k, k.linum, label, body)
+	case "$p":
+		for i, word := range body {
+			if word == "$=" {
+				return processTheorem(k, k.linum, label, body[:i])
+			}
+		}
+
+		return fmt.Errorf("theorem: %q has no proof", label)
+	}
+
+	return nil
+}
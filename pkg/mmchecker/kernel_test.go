@@ -149,9 +149,49 @@ func TestProcessAxiom_HappyPath(t *testing.T) {
 	must(processConstant(k, 1, "a"))
 	must(processVariable(k, 1, "b"))
 	must(processConstant(k, 1, "c"))
+	must(processFloatingHypothesis(k, 1, "wb", "a", "b"))
 
 	err := processAxiom(k, 1, "e", strings.Fields("a b c"))
 	if err != nil {
 		t.Error(err)
 	}
 }
+
+// TestProcessDatabase tests reading whole databases into the kernel.
+func TestProcessDatabase(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		database string
+		errPat   string
+	}{
+		{"scopes", "$c wff |- $.\n${ $v ph $. wph $f wff ph $. ax $a |- ph $. $}\n${ $v ph $. $}\n", ""},
+		{"comment in statement", "$c wff $( a comment $) |- $.\n", ""},
+		{"label reused after its scope", "$c wff $.\n$v ph $.\n${ wph $f wff ph $. $}\nwph $f wff ph $.\n", `label "wph" already defined on line 3`},
+		{"variable out of scope", "$c wff |- $.\n${ $v ph $. wph $f wff ph $. $}\nax $a |- ph $.\n", `symbol "ph" in definition of "ax" does not exist`},
+		{"constant in inner scope", "${ $c wff $. $}\n", "declared in an inner scope"},
+		{"unclosed scope", "$c wff $.\n${\n", "${ without $}"},
+		{"theorem without proof", "$c wff $.\nth $p wff $.\n", `"th" has no proof`},
+		{"dollar in symbol", "$c wff a$b $.\n", `"a$b" is not a math symbol`},
+		{"label characters", "$c wff $.\n$v ph $.\nw:ph $f wff ph $.\n", `label "w:ph" has a character other than`},
+		{"constant after variable scope", "${ $v ph $. $}\n$c ph $.\n", `symbol "ph" was declared as a variable`},
+		{"constant named like a label", "$c wff $.\n$v ph $.\n${ wph $f wff ph $. $}\n$c wph $.\n", `symbol "wph" is already a label`},
+		{"label named like a variable", "$c wff $.\n${ $v ph $. $}\nph $a wff $.\n", `label "ph" was declared as a variable`},
+		{"variable retyped", "$c wff set $.\n$v x $.\n${ wx $f wff x $. $}\nsx $f set x $.\n", `variable "x" was typed "wff" before`},
+		{"disjoint repeated", "$v x y $.\n$d x y x $.\n", `variable "x" named twice`},
+	}
+
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := processDatabase(newKernel(), parseString(tt.database))
+
+			if e := errContains(err, tt.errPat); e != nil {
+				t.Error(e)
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package mmchecker
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/internal/core"
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// outcomeError marks a JSON Lines record for an error that is not the outcome of a
+// single proof, such as a malformed statement.
+const outcomeError Outcome = "error"
+
+// jsonRecord is one line written by WriteJSONLines.
+type jsonRecord struct {
+	File    string  `json:"file,omitempty"`
+	Line    int     `json:"line,omitempty"`
+	Column  int     `json:"column,omitempty"`
+	Label   string  `json:"label,omitempty"`
+	Kind    Kind    `json:"kind,omitempty"`
+	Outcome Outcome `json:"outcome"`
+	// Code is the mmerror code of Error.
+	Code  mmerror.Code `json:"code,omitempty"`
+	Error string       `json:"error,omitempty"`
+	// Missing is the number of unknown steps of an incomplete proof.
+	Missing int `json:"missing,omitempty"`
+	// Explanation is set for invalid proofs; see Diagnostic.Explanation.
+	Explanation string `json:"explanation,omitempty"`
+}
+
+// WriteJSONLines writes one JSON object per line: one for each labeled statement,
+// then one with outcome "error" for each error that is not an invalid proof.
+func WriteJSONLines(w io.Writer, reports ...*Report) error {
+	enc := json.NewEncoder(w)
+
+	for _, r := range reports {
+		for _, stmt := range r.Statements {
+			record := jsonRecord{
+				File:    fileOf(r, stmt.Position),
+				Line:    stmt.Position.Line,
+				Column:  stmt.Position.Column,
+				Label:   stmt.Label,
+				Kind:    stmt.Kind,
+				Outcome: stmt.Outcome,
+				Missing: stmt.Missing,
+			}
+
+			if stmt.Err != nil {
+				record.Code = mmerror.CodeOf(stmt.Err)
+				record.Error = stmt.Err.Error()
+				record.Explanation = explain(stmt.Err)
+			}
+
+			if err := enc.Encode(record); err != nil {
+				return fmt.Errorf("write json lines: %w", err)
+			}
+		}
+
+		for _, d := range r.Diagnostics() {
+			if d.Proof {
+				continue
+			}
+
+			record := jsonRecord{
+				File:    fileOf(r, d.Position),
+				Line:    d.Position.Line,
+				Column:  d.Position.Column,
+				Label:   d.Label,
+				Outcome: outcomeError,
+				Code:    d.Code,
+				Error:   d.Message,
+			}
+
+			if err := enc.Encode(record); err != nil {
+				return fmt.Errorf("write json lines: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a JUnit XML document with one test suite per report. Each $p
+// statement is a test case, failed if its proof is invalid and skipped if it was
+// not checked or is incomplete. Every other error becomes an extra test case with an error.
+func WriteJUnit(w io.Writer, reports ...*Report) error {
+	var doc junitTestSuites
+
+	for _, r := range reports {
+		suite := junitTestSuite{Name: suiteName(r)}
+
+		for _, stmt := range r.Statements {
+			if stmt.Kind != KindTheorem {
+				continue
+			}
+
+			tc := junitTestCase{
+				Name:      stmt.Label,
+				Classname: suite.Name,
+				File:      fileOf(r, stmt.Position),
+				Line:      stmt.Position.Line,
+			}
+
+			switch stmt.Outcome {
+			case OutcomeInvalid:
+				tc.Failure = &junitProblem{Message: stmt.Err.Error(), Text: explain(stmt.Err)}
+				suite.Failures++
+			case OutcomeSkipped, OutcomeIncomplete:
+				tc.Skipped = &struct{}{}
+				suite.Skipped++
+			case OutcomeValid, OutcomeAccepted:
+				// passed
+			}
+
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		for _, d := range r.Diagnostics() {
+			if d.Proof {
+				continue
+			}
+
+			name := d.Label
+			if name == "" {
+				name = "error"
+			}
+
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      name,
+				Classname: suite.Name,
+				File:      fileOf(r, d.Position),
+				Line:      d.Position.Line,
+				Error:     &junitProblem{Message: d.Message, Text: d.Position.String()},
+			})
+			suite.Errors++
+		}
+
+		suite.Tests = len(suite.Cases)
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write junit: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("write junit: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("write junit: %w", err)
+	}
+
+	return nil
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// sarifSourceRoot is the base that artifact URIs relative to the working
+// directory are resolved against.
+const sarifSourceRoot = "%SRCROOT%"
+
+// newSarifArtifactLocation locates file for a SARIF consumer. A file under wd is
+// given relative to sarifSourceRoot, so that code scanning tools can match it to
+// a file in the repository; any other file gets an absolute file:// URI.
+func newSarifArtifactLocation(wd string, file string) sarifArtifactLocation {
+	if wd != "" {
+		rel := file
+		if filepath.IsAbs(file) {
+			var err error
+			if rel, err = filepath.Rel(wd, file); err != nil {
+				rel = ""
+			}
+		}
+
+		if rel != "" && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return sarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(rel)}).String(), URIBaseID: sarifSourceRoot}
+		}
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		abs = file
+	}
+
+	return sarifArtifactLocation{URI: fileURI(abs)}
+}
+
+// fileURI turns an absolute path into a file:// URI.
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// A Windows path such as C:/x.
+		path = "/" + path
+	}
+
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// WriteSARIF writes a SARIF 2.1.0 log with a single run holding every error in
+// reports, located where the file is known. Rules are the mmerror codes that
+// occur. Files under the working directory are given relative to %SRCROOT%,
+// which the log maps to the working directory; others by file:// URI.
+func WriteSARIF(w io.Writer, reports ...*Report) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "mmchecker", Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = ""
+	} else {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{
+			sarifSourceRoot: {URI: strings.TrimSuffix(fileURI(wd), "/") + "/"},
+		}
+	}
+
+	seen := map[mmerror.Code]bool{}
+
+	for _, r := range reports {
+		for _, d := range r.Diagnostics() {
+			if !seen[d.Code] {
+				seen[d.Code] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+					ID:               string(d.Code),
+					ShortDescription: sarifMessage{Text: d.Code.Description()},
+				})
+			}
+
+			result := sarifResult{
+				RuleID:  string(d.Code),
+				Level:   "error",
+				Message: sarifMessage{Text: d.Message},
+			}
+
+			if file := fileOf(r, d.Position); file != "" {
+				location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: newSarifArtifactLocation(wd, file),
+				}}
+
+				if d.Position.Line > 0 {
+					location.PhysicalLocation.Region = &sarifRegion{
+						StartLine:   d.Position.Line,
+						StartColumn: d.Position.Column,
+					}
+				}
+
+				result.Locations = []sarifLocation{location}
+			}
+
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	err = enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+	if err != nil {
+		return fmt.Errorf("write sarif: %w", err)
+	}
+
+	return nil
+}
+
+// WriteQuickfix writes each error as a file:line:col: message line, the format
+// understood by editors such as Vim and Emacs.
+func WriteQuickfix(w io.Writer, reports ...*Report) error {
+	for _, r := range reports {
+		for _, d := range r.Diagnostics() {
+			pos := d.Position
+			pos.File = fileOf(r, pos)
+
+			var err error
+
+			switch {
+			case pos.Line > 0:
+				_, err = fmt.Fprintf(w, "%s: %s\n", pos, d.Message)
+			case pos.File != "":
+				_, err = fmt.Fprintf(w, "%s: %s\n", pos.File, d.Message)
+			default:
+				_, err = fmt.Fprintln(w, d.Message)
+			}
+
+			if err != nil {
+				return fmt.Errorf("write quickfix: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// explain returns the explanation of a failed proof, or "" for other errors.
+func explain(err error) string {
+	if failure := core.AsProofFailure(err); failure != nil {
+		return failure.Explain()
+	}
+
+	return ""
+}
+
+// fileOf returns the file of pos, or the file the report is about if pos does not
+// name one.
+func fileOf(r *Report, pos Position) string {
+	if pos.File != "" {
+		return pos.File
+	}
+
+	return r.Path
+}
+
+func suiteName(r *Report) string {
+	if r.Path == "" {
+		return "database"
+	}
+
+	return r.Path
+}
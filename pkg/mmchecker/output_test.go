@@ -0,0 +1,210 @@
+package mmchecker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+const brokenDatabase = `$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+bad1 $p wff ph $= wph wph $.
+bad2 $a |- ps $.
+good $p wff ph $= wph $.
+`
+
+func brokenReport(t *testing.T) *Report {
+	t.Helper()
+
+	report, err := ValidateWithConfig(context.Background(), "", brokenDatabase, Config{Recover: true})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	report.Path = "broken.mm"
+
+	return report
+}
+
+// TestWriteJSONLines tests one record per statement and per syntax error.
+func TestWriteJSONLines(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteJSONLines(&buf, brokenReport(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []jsonRecord
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record jsonRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+
+		if record.Outcome == OutcomeInvalid && !strings.Contains(record.Explanation, "stack (bottom first)") {
+			t.Errorf("invalid proof without explanation: %+v", record)
+		}
+
+		record.Error = ""
+		record.Explanation = ""
+		got = append(got, record)
+	}
+
+	want := []jsonRecord{
+		{File: "broken.mm", Line: 3, Column: 1, Label: "wph", Kind: KindFloating, Outcome: OutcomeAccepted},
+		{File: "broken.mm", Line: 4, Column: 1, Label: "bad1", Kind: KindTheorem, Outcome: OutcomeInvalid, Code: mmerror.ConclusionMismatch},
+		{File: "broken.mm", Line: 6, Column: 1, Label: "good", Kind: KindTheorem, Outcome: OutcomeValid},
+		{File: "broken.mm", Line: 5, Column: 12, Label: "bad2", Outcome: outcomeError, Code: mmerror.UndeclaredSymbol},
+	}
+
+	if e := makeDiff(got, want); e != nil {
+		t.Error(e)
+	}
+}
+
+// TestWriteJUnit tests one test case per theorem plus one per syntax error.
+func TestWriteJUnit(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, brokenReport(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := makeDiff(len(doc.Suites), 1); e != nil {
+		t.Fatal(e)
+	}
+
+	suite := doc.Suites[0]
+
+	if e := makeDiff([]int{suite.Tests, suite.Failures, suite.Errors}, []int{3, 1, 1}); e != nil {
+		t.Error(e)
+	}
+
+	var names []string
+	for _, tc := range suite.Cases {
+		names = append(names, tc.Name)
+	}
+
+	if e := makeDiff(names, []string{"bad1", "good", "bad2"}); e != nil {
+		t.Error(e)
+	}
+
+	if suite.Cases[0].Failure == nil || suite.Cases[1].Failure != nil {
+		t.Errorf("unexpected failures %+v", suite.Cases)
+	}
+}
+
+// TestWriteSARIF tests that results carry rules and locations.
+func TestWriteSARIF(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, brokenReport(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := makeDiff(log.Version, "2.1.0"); e != nil {
+		t.Error(e)
+	}
+
+	results := log.Runs[0].Results
+	if e := makeDiff(len(results), 2); e != nil {
+		t.Fatal(e)
+	}
+
+	if e := makeDiff([]string{results[0].RuleID, results[1].RuleID}, []string{"conclusion-mismatch", "undeclared-symbol"}); e != nil {
+		t.Error(e)
+	}
+
+	if e := makeDiff(len(log.Runs[0].Tool.Driver.Rules), 2); e != nil {
+		t.Error(e)
+	}
+
+	if base := log.Runs[0].OriginalURIBaseIDs["%SRCROOT%"].URI; !strings.HasPrefix(base, "file:///") || !strings.HasSuffix(base, "/") {
+		t.Errorf("%%SRCROOT%% is %q, not a file:// URI of a directory", base)
+	}
+
+	if e := makeDiff(results[1].Locations, []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: "broken.mm", URIBaseID: "%SRCROOT%"},
+		Region:           &sarifRegion{StartLine: 5, StartColumn: 12},
+	}}}); e != nil {
+		t.Error(e)
+	}
+}
+
+// TestNewSarifArtifactLocation tests the URI shapes code scanning tools resolve.
+func TestNewSarifArtifactLocation(t *testing.T) {
+	t.Parallel()
+
+	wd := filepath.FromSlash("/src/repo")
+	root := "%SRCROOT%"
+
+	cases := []struct {
+		name string
+		file string
+		want sarifArtifactLocation
+	}{
+		{"relative", "set.mm", sarifArtifactLocation{URI: "set.mm", URIBaseID: root}},
+		{"under the working directory", filepath.FromSlash("/src/repo/db/set.mm"), sarifArtifactLocation{URI: "db/set.mm", URIBaseID: root}},
+		{"escaped", filepath.FromSlash("/src/repo/my db#1.mm"), sarifArtifactLocation{URI: "my%20db%231.mm", URIBaseID: root}},
+		{"elsewhere", filepath.FromSlash("/tmp/set.mm"), sarifArtifactLocation{URI: "file:///tmp/set.mm"}},
+	}
+
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if filepath.Separator != '/' && tt.name != "relative" {
+				t.Skip("absolute paths in this test are Unix paths")
+			}
+
+			if e := makeDiff(newSarifArtifactLocation(wd, tt.file), tt.want); e != nil {
+				t.Error(e)
+			}
+		})
+	}
+}
+
+// TestWriteQuickfix tests the file:line:col: message format.
+func TestWriteQuickfix(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := WriteQuickfix(&buf, brokenReport(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if e := makeDiff(len(lines), 2); e != nil {
+		t.Fatal(e)
+	}
+
+	if !strings.HasPrefix(lines[0], "broken.mm:4:") {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+
+	if e := makeDiff(lines[1], `broken.mm:5:12: reading statement in $a: Token "ps" is not an active symbol; did you mean "ph"?`); e != nil {
+		t.Error(e)
+	}
+}
@@ -3,35 +3,113 @@ package mmchecker
 import (
 	"context"
 	"errors"
+	"fmt"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/internal/core"
 )
 
-func Validate(ctx context.Context, path string, content string) error {
+// Validate verifies a database given either as a path or as content, but not both.
+//
+// The returned report is non-nil whenever the arguments are valid. Its Err field
+// holds the same error as the second return value.
+//
+// Reading stops once ctx is done. The error then satisfies errors.Is with ctx.Err()
+// and names the label being processed; see CanceledAt.
+func Validate(ctx context.Context, path string, content string) (*Report, error) {
+	return ValidateWithConfig(ctx, path, content, Config{})
+}
+
+// ValidateWithConfig is like Validate but lets the caller configure the run.
+func ValidateWithConfig(ctx context.Context, path string, content string, cfg Config) (*Report, error) {
+	_, report, err := load(ctx, path, content, cfg)
+
+	return report, err
+}
+
+// Load reads and verifies a database like ValidateWithConfig and returns a handle
+// for querying it. The database is returned even when verification fails, in which
+// case it holds everything read up to the failure, including a theorem whose
+// proof failed.
+func Load(ctx context.Context, path string, content string, cfg Config) (*Database, error) {
+	mm, report, err := load(ctx, path, content, cfg)
+	if mm == nil {
+		return nil, err
+	}
+
+	return newDatabase(mm, report), err
+}
+
+// load runs the core verifier. The verifier is nil only when the arguments are invalid
+// or the database cannot be opened.
+func load(ctx context.Context, path string, content string, cfg Config) (*core.MM, *Report, error) {
 	params := 0
 	if path != "" {
 		params++
 	}
+
 	if content != "" {
 		params++
 	}
+
 	switch params {
 	case 0:
-		return errors.New("no parameters given")
+		return nil, nil, errors.New("no parameters given")
 	case 1:
 		// continue
 	default:
-		return errors.New("too many parameters given")
-	}
-	var parsed [][]string
-	var err error
-	switch {
-	case path != "":
-		parsed, err = parseFile(path)
-		if err != nil {
-			return err
+		return nil, nil, errors.New("too many parameters given")
+	}
+
+	if err := cfg.Selection.core().Validate(); err != nil {
+		return nil, nil, fmt.Errorf("selection: %w", err)
+	}
+
+	if cfg.Selection.Dependencies {
+		cfg.Selection = withDependencies(ctx, path, content, cfg)
+	}
+
+	mm := newMM(cfg)
+
+	toks, err := openToks(path, content, cfg)
+	if err != nil {
+		err = fmt.Errorf("validate: %w", err)
+
+		return nil, newReport(path, nil, err), err
+	}
+
+	if err := mm.ReadContext(ctx, toks); err != nil && !core.IsEOF(err) {
+		if path != "" {
+			err = fmt.Errorf("validate %q: %w", path, err)
+		} else {
+			err = fmt.Errorf("validate: %w", err)
+		}
+
+		report := newReport(path, mm.Results, err)
+		if len(mm.Errors) > 0 {
+			report.Errors = mm.Errors
 		}
-	case content != "":
-		parsed = parseString(content)
+
+		return mm, report, err
 	}
 
-	k := newKernel()
+	return mm, newReport(path, mm.Results, nil), nil
+}
+
+// openToks starts reading the database from path or content.
+func openToks(path string, content string, cfg Config) (*core.Toks, error) {
+	if path == "" {
+		toks := core.NewStringToks(content)
+		toks.IncludePath = cfg.IncludePath
+
+		return toks, nil
+	}
+
+	toks, err := core.NewToks(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	toks.IncludePath = cfg.IncludePath
+
+	return toks, nil
 }
@@ -0,0 +1,348 @@
+package mmchecker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+const tinyDatabase = `$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+idi.1 $e |- ph $.
+idi $p |- ph $= ( ) B $.
+ax $a |- ph $.
+`
+
+// TestValidate tests the report for a small valid database.
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	report, err := Validate(context.Background(), "", tinyDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Statement{
+		{Label: "wph", Kind: KindFloating, Position: Position{Line: 3, Column: 1}, Outcome: OutcomeAccepted},
+		{Label: "idi.1", Kind: KindEssential, Position: Position{Line: 4, Column: 1}, Outcome: OutcomeAccepted},
+		{Label: "idi", Kind: KindTheorem, Position: Position{Line: 5, Column: 1}, Outcome: OutcomeValid},
+		{Label: "ax", Kind: KindAxiom, Position: Position{Line: 6, Column: 1}, Outcome: OutcomeAccepted},
+	}
+
+	if e := makeDiff(report.Statements, want); e != nil {
+		t.Error(e)
+	}
+
+	if e := makeDiff(report.Totals, Totals{
+		Statements: 4,
+		Axioms:     1,
+		Theorems:   1,
+		Hypotheses: 2,
+		Valid:      1,
+	}); e != nil {
+		t.Error(e)
+	}
+
+	if !report.OK() {
+		t.Error("report should be OK")
+	}
+}
+
+// TestValidate_InvalidProof tests that a bad proof is recorded as the fatal error.
+func TestValidate_InvalidProof(t *testing.T) {
+	t.Parallel()
+
+	report, err := Validate(context.Background(), "", `$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+idi.1 $e |- ph $.
+idi $p |- ph $= ( ) A $.
+`)
+	if !IsVerificationFailure(err) {
+		t.Fatalf("expected verification failure, got %v", err)
+	}
+
+	if report.OK() {
+		t.Error("report should not be OK")
+	}
+
+	if e := makeDiff(report.Totals.Invalid, 1); e != nil {
+		t.Error(e)
+	}
+
+	last := report.Statements[len(report.Statements)-1]
+	if last.Label != "idi" || last.Outcome != OutcomeInvalid || last.Err == nil {
+		t.Errorf("unexpected last statement %+v", last)
+	}
+}
+
+// TestErrorPosition tests that a syntax error says where it happened.
+func TestErrorPosition(t *testing.T) {
+	t.Parallel()
+
+	_, err := Validate(context.Background(), "", "$c |- $.\n  ax $a |- x $.\n")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	pos, labelPos, ok := ErrorPosition(err)
+	if !ok {
+		t.Fatalf("no position in %v", err)
+	}
+
+	if e := makeDiff(pos, Position{Line: 2, Column: 12}); e != nil {
+		t.Error(e)
+	}
+
+	if e := makeDiff(labelPos, Position{Line: 2, Column: 3}); e != nil {
+		t.Error(e)
+	}
+
+	if e := makeDiff(pos.String(), "2:12"); e != nil {
+		t.Error(e)
+	}
+}
+
+// TestValidate_Recover tests that a recovering run reports every error.
+func TestValidate_Recover(t *testing.T) {
+	t.Parallel()
+
+	report, err := ValidateWithConfig(context.Background(), "", `$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+bad1 $p wff ph $= wph wph $.
+bad2 $a |- ps $.
+uses1 $p wff ph $= wph bad1 $.
+`, Config{Recover: true})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if e := makeDiff(len(report.Errors), 2); e != nil {
+		t.Fatal(e)
+	}
+
+	for i, label := range []string{"bad1", "bad2"} {
+		if e := errContains(report.Errors[i], label); e != nil {
+			t.Error(e)
+		}
+	}
+
+	last := report.Statements[len(report.Statements)-1]
+	if last.Label != "uses1" || last.Outcome != OutcomeValid {
+		t.Errorf("unexpected last statement %+v", last)
+	}
+}
+
+// TestDiagnostics_Suggestions tests did-you-mean suggestions.
+func TestDiagnostics_Suggestions(t *testing.T) {
+	t.Parallel()
+
+	report, err := Validate(context.Background(), "", "$c |- wff $.\n$v ph $.\nwph $f wff ph $.\nax $a |- pf $.\n")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	diagnostics := report.Diagnostics()
+	if e := makeDiff(len(diagnostics), 1); e != nil {
+		t.Fatal(e)
+	}
+
+	if e := makeDiff(diagnostics[0].Suggestions, []string{"ph"}); e != nil {
+		t.Error(e)
+	}
+
+	if e := errContains(err, `did you mean "ph"?`); e != nil {
+		t.Error(e)
+	}
+}
+
+// TestValidate_ErrorCodes tests that errors carry their mmerror code and label.
+func TestValidate_ErrorCodes(t *testing.T) {
+	t.Parallel()
+
+	const header = "$c |- wff $.\n$v ph ps $.\nwph $f wff ph $.\nwps $f wff ps $.\n"
+
+	tests := []struct {
+		name     string
+		database string
+		code     mmerror.Code
+		label    string
+	}{
+		{"unclosed block", "$c a $.\n${\n", mmerror.UnclosedBlock, ""},
+		{"undeclared symbol", "$c |- $.\nax $a |- x $.\n", mmerror.UndeclaredSymbol, "ax"},
+		{"duplicate label", "$c a $.\nx $a a $.\nx $a a $.\n", mmerror.DuplicateLabel, "x"},
+		{"include failed", "$[ missing.mm $]\n", mmerror.IncludeFailed, ""},
+		{"unknown label", header + "th $p |- ph $= nope $.\n", mmerror.UnknownLabel, "th"},
+		{"stack underflow", header + "ax $a |- ph $.\nth $p |- ph $= ax $.\n", mmerror.StackUnderflow, "th"},
+		{
+			"hypothesis mismatch",
+			header + "${ ax.1 $e |- ph $. ax $a |- ph $. $}\nth $p |- ph $= wph wph ax $.\n",
+			mmerror.HypothesisMismatch, "th",
+		},
+		{"conclusion mismatch", header + "th $p |- ph $= wph $.\n", mmerror.ConclusionMismatch, "th"},
+		{
+			"disjoint violation",
+			header + "${ $d ph ps $. ax $a |- ph ps $. $}\nth $p |- ph ph $= wph wph ax $.\n",
+			mmerror.DisjointViolation, "th",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Validate(context.Background(), "", tt.database)
+			if !errors.Is(err, tt.code) {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+
+			var mmErr *mmerror.Error
+			if !errors.As(err, &mmErr) {
+				t.Fatalf("expected an *mmerror.Error, got %v", err)
+			}
+
+			if e := makeDiff([]string{string(mmErr.Code), mmErr.Label}, []string{string(tt.code), tt.label}); e != nil {
+				t.Error(e)
+			}
+
+			if !mmErr.Position.IsValid() {
+				t.Errorf("no position in %v", mmErr)
+			}
+		})
+	}
+}
+
+// TestValidate_Limits tests that a proof over a limit is skipped and reported.
+func TestValidate_Limits(t *testing.T) {
+	t.Parallel()
+
+	report, err := ValidateWithConfig(context.Background(), "", dependencyDatabase, Config{
+		Recover: true,
+		Limits:  Limits{MaxSubstitutionLength: 1},
+	})
+	if !errors.Is(err, mmerror.LimitExceeded) {
+		t.Fatalf("expected a limit error, got %v", err)
+	}
+
+	var codes []mmerror.Code
+	for _, d := range report.Diagnostics() {
+		codes = append(codes, d.Code)
+	}
+
+	if e := makeDiff(codes, []mmerror.Code{mmerror.LimitExceeded, mmerror.LimitExceeded}); e != nil {
+		t.Error(e)
+	}
+
+	if e := makeDiff([]int{report.Totals.Skipped, report.Totals.Invalid, report.Totals.Valid}, []int{2, 0, 1}); e != nil {
+		t.Error(e)
+	}
+}
+
+const incompleteDatabase = `$c ( ) -> wff |- $.
+$v ph ps $.
+wph $f wff ph $.
+wps $f wff ps $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+th.1 $e |- ph $.
+`
+
+// TestValidate_IncompleteProof tests proofs with unknown steps.
+func TestValidate_IncompleteProof(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		proof   string
+		allow   bool
+		code    mmerror.Code
+		outcome Outcome
+		missing int
+	}{
+		{"normal", "wph wps th.1 ? ax-mp", false, mmerror.IncompleteProof, OutcomeIncomplete, 1},
+		{"normal allowed", "wph wps th.1 ? ax-mp", true, "", OutcomeIncomplete, 1},
+		{"compressed allowed", "( ax-mp ) AB? ?D", true, "", OutcomeIncomplete, 2},
+		{"only unknown", "?", true, "", OutcomeIncomplete, 1},
+		{"wrong", "wps wph th.1 ? ax-mp", true, mmerror.HypothesisMismatch, OutcomeInvalid, 0},
+		{"wrong conclusion", "wph", true, mmerror.ConclusionMismatch, OutcomeInvalid, 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			report, err := ValidateWithConfig(
+				context.Background(),
+				"",
+				incompleteDatabase+"th $p |- ps $= "+tt.proof+" $.\n",
+				Config{AllowIncomplete: tt.allow},
+			)
+			if e := makeDiff(mmerror.CodeOf(err), tt.code); e != nil {
+				t.Fatal(e)
+			}
+
+			if e := makeDiff(report.OK(), err == nil); e != nil {
+				t.Error(e)
+			}
+
+			last := report.Statements[len(report.Statements)-1]
+			if e := makeDiff([]any{last.Outcome, last.Missing}, []any{tt.outcome, tt.missing}); e != nil {
+				t.Error(e)
+			}
+
+			if tt.outcome == OutcomeIncomplete && report.Totals.Incomplete != 1 {
+				t.Errorf("expected 1 incomplete proof, got %+v", report.Totals)
+			}
+		})
+	}
+}
+
+// TestValidate_Parameters tests argument checking.
+func TestValidate_Parameters(t *testing.T) {
+	t.Parallel()
+
+	report, err := Validate(context.Background(), "", "")
+	if e := errContains(err, "no parameters given"); e != nil {
+		t.Error(e)
+	}
+
+	if d := report.Diagnostics(); d != nil {
+		t.Errorf("nil report has diagnostics %v", d)
+	}
+
+	_, err = Validate(context.Background(), "a.mm", "$c a $.")
+	if e := errContains(err, "too many parameters given"); e != nil {
+		t.Error(e)
+	}
+}
+
+// TestValidate_Canceled tests that a cancelled context stops the run.
+func TestValidate_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Validate(ctx, "", tinyDatabase)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if _, ok := CanceledAt(err); !ok {
+		t.Error("CanceledAt should recognize the error")
+	}
+
+	if IsVerificationFailure(err) {
+		t.Error("cancellation is not a verification failure")
+	}
+}
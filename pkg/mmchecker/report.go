@@ -0,0 +1,232 @@
+package mmchecker
+
+import (
+	"errors"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/internal/core"
+	"github.com/gregory-nisbet/mmchecker/pkg/mmerror"
+)
+
+// Kind is the type of a labeled statement.
+type Kind string
+
+const (
+	KindAxiom     Kind = "$a"
+	KindTheorem   Kind = "$p"
+	KindEssential Kind = "$e"
+	KindFloating  Kind = "$f"
+)
+
+// Outcome is what the verifier concluded about a statement.
+type Outcome string
+
+const (
+	// OutcomeAccepted is used for axioms and hypotheses, which have nothing to prove.
+	OutcomeAccepted Outcome = "accepted"
+	// OutcomeValid is used for theorems whose proof was checked and is correct.
+	OutcomeValid Outcome = "valid"
+	// OutcomeInvalid is used for theorems whose proof was checked and is wrong.
+	OutcomeInvalid Outcome = "invalid"
+	// OutcomeSkipped is used for theorems whose proof was not checked.
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeIncomplete is used for theorems whose proof has unknown steps, written
+	// ?, and is correct as far as it goes.
+	OutcomeIncomplete Outcome = "incomplete"
+)
+
+// Position is a location in a database; see mmerror.Position.
+type Position = mmerror.Position
+
+// ErrorPosition returns where the statement that caused err is, and where its
+// label starts. The label position is the zero Position for unlabeled
+// statements. The boolean is false if err carries no position.
+func ErrorPosition(err error) (Position, Position, bool) {
+	mmErr := core.AsMMError(err)
+	if mmErr == nil || !mmErr.Pos.IsValid() {
+		return Position{}, Position{}, false
+	}
+
+	return newPosition(mmErr.Pos), newPosition(mmErr.LabelPos), true
+}
+
+func newPosition(pos core.Pos) Position {
+	return pos.Position()
+}
+
+// Statement is the verification result for one labeled statement.
+type Statement struct {
+	Label    string
+	Kind     Kind
+	Position Position
+	Outcome  Outcome
+	// Err is set when Outcome is OutcomeInvalid or OutcomeIncomplete, and when it
+	// is OutcomeSkipped because the proof went over one of the Limits.
+	Err error
+	// Missing is the number of unknown steps when Outcome is OutcomeIncomplete.
+	Missing int
+}
+
+// Totals counts the statements in a report.
+type Totals struct {
+	Statements int
+	Axioms     int
+	Theorems   int
+	Hypotheses int
+	Valid      int
+	Invalid    int
+	Skipped    int
+	Incomplete int
+}
+
+// Report is the result of verifying a database.
+type Report struct {
+	// Path is the file that was verified. It is empty for in-memory content.
+	Path string
+	// Statements lists every labeled statement read, in source order.
+	Statements []Statement
+	Totals     Totals
+	// Err is the error that ended the run, if any. With Config.Recover it
+	// joins every diagnostic together.
+	Err error
+	// Errors lists the diagnostics one by one, in the order they were found.
+	Errors []error
+}
+
+// Diagnostic is one error from a run, ready to be shown to a user.
+type Diagnostic struct {
+	// Code is the kind of error.
+	Code mmerror.Code
+	// Position is where the problem was found. It is the zero Position if
+	// the error is not tied to a place in the database, such as a missing file.
+	Position Position
+	// Label is the label of the statement at fault, if it has one.
+	Label string
+	// Message describes the problem without repeating its position.
+	Message string
+	// Proof is true if the statement was well formed but its proof is invalid.
+	Proof bool
+	// Explanation describes over several lines why a proof step failed: the
+	// proof stack, the substitution, a diff of the statements that should
+	// have matched and any violated $d pair. It is empty for other errors.
+	Explanation string
+	// Suggestions are similar names, best first, when the error is about a
+	// label, symbol or variable that is not available.
+	Suggestions []string
+	Err         error
+}
+
+// Diagnostics describes each error in r.Errors. A nil Report has none.
+func (r *Report) Diagnostics() []Diagnostic {
+	if r == nil {
+		return nil
+	}
+
+	out := make([]Diagnostic, 0, len(r.Errors))
+
+	for _, err := range r.Errors {
+		d := Diagnostic{
+			Code:    mmerror.CodeOf(err),
+			Message: err.Error(),
+			Proof:   IsVerificationFailure(err),
+			Err:     err,
+		}
+
+		if mmErr := core.AsMMError(err); mmErr != nil {
+			d.Position = newPosition(mmErr.Pos)
+			d.Label = string(mmErr.Label)
+			d.Message = mmErr.Message()
+		}
+
+		if verifyErr := core.AsVerifyError(err); verifyErr != nil {
+			d.Label = string(verifyErr.Label)
+		}
+
+		d.Explanation = explain(err)
+
+		if notFound := core.AsNotFoundError(err); notFound != nil {
+			d.Suggestions = notFound.Suggestions
+		}
+
+		out = append(out, d)
+	}
+
+	return out
+}
+
+// OK reports whether the database was read completely and no proof was invalid.
+// Incomplete proofs only make it false if they were not allowed, since they then
+// end the run with an error.
+func (r *Report) OK() bool {
+	return r.Err == nil && r.Totals.Invalid == 0
+}
+
+// newReport builds a report from the results recorded by the core verifier.
+func newReport(path string, results []core.Result, err error) *Report {
+	report := &Report{
+		Path:       path,
+		Statements: make([]Statement, 0, len(results)),
+		Err:        err,
+	}
+
+	if err != nil {
+		report.Errors = []error{err}
+	}
+
+	for _, result := range results {
+		stmt := newStatement(result)
+
+		switch stmt.Kind {
+		case KindAxiom:
+			report.Totals.Axioms++
+		case KindTheorem:
+			report.Totals.Theorems++
+		case KindEssential, KindFloating:
+			report.Totals.Hypotheses++
+		}
+
+		switch stmt.Outcome {
+		case OutcomeSkipped:
+			report.Totals.Skipped++
+		case OutcomeInvalid:
+			report.Totals.Invalid++
+		case OutcomeValid:
+			report.Totals.Valid++
+		case OutcomeIncomplete:
+			report.Totals.Incomplete++
+		case OutcomeAccepted:
+			// nothing to count
+		}
+
+		report.Totals.Statements++
+		report.Statements = append(report.Statements, stmt)
+	}
+
+	return report
+}
+
+// newStatement converts the result recorded by the core verifier for one statement.
+func newStatement(result core.Result) Statement {
+	stmt := Statement{
+		Label:    string(result.Label),
+		Kind:     Kind(result.SType),
+		Position: newPosition(result.Pos),
+		Outcome:  OutcomeAccepted,
+		Err:      result.Err,
+		Missing:  result.Missing,
+	}
+
+	if stmt.Kind == KindTheorem {
+		switch {
+		case !result.Checked:
+			stmt.Outcome = OutcomeSkipped
+		case errors.Is(result.Err, mmerror.IncompleteProof):
+			stmt.Outcome = OutcomeIncomplete
+		case result.Err != nil:
+			stmt.Outcome = OutcomeInvalid
+		default:
+			stmt.Outcome = OutcomeValid
+		}
+	}
+
+	return stmt
+}
@@ -0,0 +1,132 @@
+package mmchecker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gregory-nisbet/mmchecker/pkg/internal/core"
+)
+
+// Selection chooses the theorems whose proofs are checked; see Config.Selection. A
+// theorem is selected if it matches any of the criteria, and the zero Selection
+// selects every theorem.
+type Selection struct {
+	// Ranges are inclusive ranges of labels, in the order they appear.
+	Ranges []LabelRange
+	// Globs are shell patterns such as "syl*", matched as by path.Match.
+	Globs []string
+	// Patterns match anywhere in a label unless they are anchored.
+	Patterns []*regexp.Regexp
+	// Labels are selected as they are.
+	Labels []string
+	// Dependencies also selects every theorem whose proof a selected theorem
+	// uses, directly or not. The database is then read twice.
+	Dependencies bool
+}
+
+// LabelRange is an inclusive range of labels. An empty First starts at the
+// beginning of the database and an empty Last runs to its end.
+type LabelRange struct {
+	First string
+	Last  string
+}
+
+var errBadRange = errors.New(`label range must be "FIRST..LAST"`)
+
+// ParseLabelRange parses a range written as "FIRST..LAST". Either label may be
+// left out, as in "syl..", which runs from syl to the end.
+func ParseLabelRange(s string) (LabelRange, error) {
+	first, last, ok := strings.Cut(s, "..")
+	if !ok || strings.Contains(last, "..") {
+		return LabelRange{}, fmt.Errorf("%w: %q", errBadRange, s)
+	}
+
+	return LabelRange{First: first, Last: last}, nil
+}
+
+// core translates the selection, apart from Dependencies.
+func (s Selection) core() core.Selection {
+	out := core.Selection{
+		Globs:    s.Globs,
+		Patterns: s.Patterns,
+	}
+
+	for _, r := range s.Ranges {
+		out.Ranges = append(out.Ranges, core.LabelRange{First: core.Label(r.First), Last: core.Label(r.Last)})
+	}
+
+	for _, label := range s.Labels {
+		out.Labels = append(out.Labels, core.Label(label))
+	}
+
+	return out
+}
+
+// withDependencies reads the database once without checking proofs, and adds to
+// cfg.Selection every theorem that the theorems it selects depend on. The read
+// recovers from errors if cfg.Recover is set, like the run that follows. Errors
+// are left for that run to report: the dependencies found before the read
+// stopped are still added, and the run stops at the same place.
+func withDependencies(ctx context.Context, path string, content string, cfg Config) Selection {
+	toks, err := openToks(path, content, cfg)
+	if err != nil {
+		return cfg.Selection
+	}
+
+	deps := &dependencyVisitor{uses: map[core.Label][]core.Label{}}
+
+	opts := newOptions(cfg)
+	opts.Logger = nil
+	opts.Progress = nil
+	opts.ParseOnly = true
+	opts.Visitor = deps
+
+	// Any error is reported by the run that follows.
+	_ = core.NewMMWithOptions(opts).ReadContext(ctx, toks)
+
+	out := cfg.Selection
+	out.Labels = append([]string(nil), out.Labels...)
+	seen := map[core.Label]bool{}
+	queue := deps.selected
+
+	for len(queue) > 0 {
+		label := queue[0]
+		queue = queue[1:]
+
+		if seen[label] {
+			continue
+		}
+
+		seen[label] = true
+		out.Labels = append(out.Labels, string(label))
+
+		for _, used := range deps.uses[label] {
+			if _, ok := deps.uses[used]; ok && !seen[used] {
+				queue = append(queue, used)
+			}
+		}
+	}
+
+	return out
+}
+
+// dependencyVisitor records the labels used by each proof, and which theorems
+// are selected.
+type dependencyVisitor struct {
+	core.NopVisitor
+	uses     map[core.Label][]core.Label
+	selected []core.Label
+}
+
+func (d *dependencyVisitor) OnTheorem(label core.Label, _ *core.Assertion, proof []string, result core.Result) error {
+	d.uses[label] = core.ProofLabels(proof)
+
+	if result.Selected {
+		d.selected = append(d.selected, label)
+	}
+
+	return nil
+}
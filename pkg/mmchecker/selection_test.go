@@ -0,0 +1,125 @@
+package mmchecker
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+const dependencyDatabase = `$c |- wff $.
+$v ph $.
+wph $f wff ph $.
+ax $a wff ph $.
+lem $p wff ph $= wph ax $.
+other $p wff ph $= wph $.
+thm $p wff ph $= wph lem $.
+`
+
+// TestValidate_Selection tests which theorems are checked for a selection.
+func TestValidate_Selection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		sel  Selection
+		want []string
+	}{
+		{"everything", Selection{}, []string{"lem", "other", "thm"}},
+		{"label", Selection{Labels: []string{"thm"}}, []string{"thm"}},
+		{"dependencies", Selection{Labels: []string{"thm"}, Dependencies: true}, []string{"lem", "thm"}},
+		{"range", Selection{Ranges: []LabelRange{{First: "ax", Last: "other"}}}, []string{"lem", "other"}},
+		{"glob", Selection{Globs: []string{"?h*"}}, []string{"thm"}},
+		{"pattern", Selection{Patterns: []*regexp.Regexp{regexp.MustCompile("^o")}}, []string{"other"}},
+		{"nothing with dependencies", Selection{Labels: []string{"none"}, Dependencies: true}, nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			report, err := ValidateWithConfig(context.Background(), "", dependencyDatabase, Config{Selection: tt.sel})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var checked []string
+
+			for _, stmt := range report.Statements {
+				if stmt.Outcome == OutcomeValid {
+					checked = append(checked, stmt.Label)
+				}
+			}
+
+			if e := makeDiff(checked, tt.want); e != nil {
+				t.Error(e)
+			}
+		})
+	}
+}
+
+// TestValidate_DependenciesRecover tests that an error elsewhere in the database
+// does not keep dependencies from being checked when recovering.
+func TestValidate_DependenciesRecover(t *testing.T) {
+	t.Parallel()
+
+	report, err := ValidateWithConfig(context.Background(), "", dependencyDatabase+"bad $a |- ps $.\n", Config{
+		Selection: Selection{Labels: []string{"thm"}, Dependencies: true},
+		Recover:   true,
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var checked []string
+
+	for _, stmt := range report.Statements {
+		if stmt.Outcome == OutcomeValid {
+			checked = append(checked, stmt.Label)
+		}
+	}
+
+	if e := makeDiff(checked, []string{"lem", "thm"}); e != nil {
+		t.Error(e)
+	}
+}
+
+// TestValidate_BadSelection tests that a malformed glob is rejected.
+func TestValidate_BadSelection(t *testing.T) {
+	t.Parallel()
+
+	_, err := ValidateWithConfig(context.Background(), "", dependencyDatabase, Config{
+		Selection: Selection{Globs: []string{"["}},
+	})
+	if e := errContains(err, "syntax error in pattern"); e != nil {
+		t.Error(e)
+	}
+}
+
+// TestParseLabelRange tests the FIRST..LAST syntax.
+func TestParseLabelRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want LabelRange
+		err  string
+	}{
+		{"a..b", LabelRange{First: "a", Last: "b"}, ""},
+		{"syl..", LabelRange{First: "syl"}, ""},
+		{"..ax-mp", LabelRange{Last: "ax-mp"}, ""},
+		{"syl", LabelRange{}, "must be"},
+		{"a..b..c", LabelRange{}, "must be"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLabelRange(tt.in)
+		if e := errContains(err, tt.err); e != nil {
+			t.Errorf("%q: %v", tt.in, e)
+		}
+
+		if e := makeDiff(got, tt.want); e != nil {
+			t.Errorf("%q: %v", tt.in, e)
+		}
+	}
+}
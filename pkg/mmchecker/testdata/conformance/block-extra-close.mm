@@ -0,0 +1,9 @@
+$( expect: fail unexpected-token
+   A $} must close a ${. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+$}
@@ -0,0 +1,10 @@
+$( expect: fail unclosed-block
+   Every ${ has a matching $}. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  ax $a |- ph $.
@@ -0,0 +1,9 @@
+$( expect: fail malformed-proof
+   Compressed proofs only use the letters A to Z. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ph $= ( ) a $.
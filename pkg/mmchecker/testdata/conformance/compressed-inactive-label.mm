@@ -0,0 +1,12 @@
+$( expect: fail inactive-hypothesis
+   Hypotheses in the list must be active. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  h $e |- ph $.
+$}
+th $p |- ph $= ( h ) B $.
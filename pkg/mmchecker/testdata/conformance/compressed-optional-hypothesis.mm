@@ -0,0 +1,13 @@
+$( expect: pass
+   Optional hypotheses are listed like other labels. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  k.1 $e wff ps $.
+  k $a wff ph $.
+$}
+th $p wff ph $= ( wps k ) ABBC $.
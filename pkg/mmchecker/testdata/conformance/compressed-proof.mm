@@ -0,0 +1,18 @@
+$( expect: pass
+   A compressed proof numbers the mandatory hypotheses, then the listed labels. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+ax-1 $a |- ( ph -> ( ps -> ph ) ) $.
+${
+  h $e |- ph $.
+  th $p |- ( ps -> ph ) $= ( wi ax-1 ax-mp ) ABADCABEF $.
+$}
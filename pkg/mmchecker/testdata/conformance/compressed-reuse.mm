@@ -0,0 +1,18 @@
+$( expect: pass
+   Z saves a step, which later numbers past the labels refer to. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+ax-1 $a |- ( ph -> ( ps -> ph ) ) $.
+${
+  h $e |- ( ph -> ps ) $.
+  th $p |- ( ph -> ( ph -> ps ) ) $= ( wi ax-1 ax-mp ) ABDZAGDCGAEF $.
+$}
@@ -0,0 +1,9 @@
+$( expect: fail malformed-proof
+   Z may not come between the letters of a number. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ph $= ( ) UZA $.
@@ -0,0 +1,29 @@
+$( expect: pass
+   Numbers above 20 take a letter from U to Y before the last letter. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+a0 $a wff ph $.
+a1 $a wff ph $.
+a2 $a wff ph $.
+a3 $a wff ph $.
+a4 $a wff ph $.
+a5 $a wff ph $.
+a6 $a wff ph $.
+a7 $a wff ph $.
+a8 $a wff ph $.
+a9 $a wff ph $.
+a10 $a wff ph $.
+a11 $a wff ph $.
+a12 $a wff ph $.
+a13 $a wff ph $.
+a14 $a wff ph $.
+a15 $a wff ph $.
+a16 $a wff ph $.
+a17 $a wff ph $.
+a18 $a wff ph $.
+a19 $a wff ph $.
+th $p wff ph $= ( a0 a1 a2 a3 a4 a5 a6 a7 a8 a9 a10 a11 a12 a13 a14 a15 a16 a17 a18 a19 ) AUA $.
@@ -0,0 +1,9 @@
+$( expect: fail malformed-proof
+   The label list ends with a parenthesis. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ph $= ( wph A $.
@@ -0,0 +1,9 @@
+$( expect: fail unknown-label
+   Labels in the list must exist. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ph $= ( nope ) B $.
@@ -0,0 +1,19 @@
+$( expect: pass
+   The letters of a compressed proof may be split by whitespace. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+ax-1 $a |- ( ph -> ( ps -> ph ) ) $.
+${
+  h $e |- ph $.
+  th $p |- ( ps -> ph ) $= ( wi ax-1 ax-mp ) ABA
+    DCA BEF $.
+$}
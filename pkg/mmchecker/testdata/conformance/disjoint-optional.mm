@@ -0,0 +1,18 @@
+$( expect: pass
+   A $d restriction on variables that are not mandatory does not bind users of an assertion. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+$c set $.
+$v x y z $.
+vx $f set x $.
+vy $f set y $.
+vz $f set z $.
+${
+  $d x y $.
+  ax $a |- ph $.
+$}
+th $p |- ps $= wps ax $.
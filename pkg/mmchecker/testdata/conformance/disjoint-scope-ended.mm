@@ -0,0 +1,21 @@
+$( expect: fail disjoint-violation
+   A $d restriction ends with its block. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+$c set $.
+$v x y z $.
+vx $f set x $.
+vy $f set y $.
+vz $f set z $.
+${
+  $d x y $.
+  ax-dv $a |- x y $.
+$}
+${
+  $d y z $.
+$}
+th $p |- y z $= vy vz ax-dv $.
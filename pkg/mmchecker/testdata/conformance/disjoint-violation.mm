@@ -0,0 +1,18 @@
+$( expect: fail disjoint-violation
+   Variables substituted for a $d pair must be disjoint. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+$c set $.
+$v x y z $.
+vx $f set x $.
+vy $f set y $.
+vz $f set z $.
+${
+  $d x y $.
+  ax-dv $a |- x y $.
+$}
+th $p |- z z $= vz vz ax-dv $.
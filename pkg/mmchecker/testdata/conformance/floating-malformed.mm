@@ -0,0 +1,5 @@
+$( expect: fail malformed-statement
+   A $f statement is a typecode followed by a variable. $)
+$c wff $.
+$v ph $.
+wph $f wff $.
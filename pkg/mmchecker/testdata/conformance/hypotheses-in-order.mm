@@ -0,0 +1,14 @@
+$( expect: pass
+   Mandatory hypotheses are used in the order they appear, $f and $e mixed. $)
+$c wff |- ( ) -> $.
+$v ph ps $.
+wph $f wff ph $.
+${
+  e1 $e |- ph $.
+  wps $f wff ps $.
+  ax $a |- ( ps -> ph ) $.
+$}
+${
+  h $e |- ph $.
+  th $p |- ( ph -> ph ) $= wph h wph ax $.
+$}
@@ -0,0 +1,14 @@
+$( expect: fail hypothesis-mismatch
+   Mandatory hypotheses are not grouped by kind. $)
+$c wff |- ( ) -> $.
+$v ph ps $.
+wph $f wff ph $.
+${
+  e1 $e |- ph $.
+  wps $f wff ps $.
+  ax $a |- ( ps -> ph ) $.
+$}
+${
+  h $e |- ph $.
+  th $p |- ( ph -> ph ) $= wph wph h ax $.
+$}
@@ -0,0 +1,3 @@
+$( expect: fail include-failed
+   An included file must exist. $)
+$[ include/missing.mm $]
@@ -0,0 +1,5 @@
+$( expect: pass
+   A file included twice is only read once. $)
+$[ include/header.mm $]
+$[ include/header.mm $]
+ax $a |- ph $.
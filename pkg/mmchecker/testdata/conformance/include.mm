@@ -0,0 +1,4 @@
+$( expect: pass
+   $[ $] reads another file in place. $)
+$[ include/header.mm $]
+ax $a |- ph $.
@@ -0,0 +1,4 @@
+$( Included by the include cases. $)
+$c wff |- $.
+$v ph $.
+wph $f wff ph $.
@@ -0,0 +1,10 @@
+$( expect: fail duplicate-label
+   A label may only be defined once. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+ax $a |- ph $.
+ax $a |- ps $.
@@ -0,0 +1,14 @@
+$( expect: fail duplicate-label
+   A label may not be reused, even by a hypothesis whose scope has ended. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  h $e |- ph $.
+$}
+${
+  h $e |- ps $.
+$}
@@ -0,0 +1,7 @@
+$( expect: fail duplicate-symbol
+   A label may not match a variable, even once its scope has ended. $)
+$c wff $.
+${
+  $v x $.
+$}
+x $a wff $.
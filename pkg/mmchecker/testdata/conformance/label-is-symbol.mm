@@ -0,0 +1,9 @@
+$( expect: fail duplicate-symbol
+   A label may not also be a math symbol. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+ph $a |- ph $.
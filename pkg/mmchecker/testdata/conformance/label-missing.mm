@@ -0,0 +1,9 @@
+$( expect: fail missing-label
+   $a statements need a label. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+$a |- ph $.
@@ -0,0 +1,7 @@
+$( expect: pass
+   Comments may appear between any two tokens. $)
+$( leading $)
+$c wff $( between $) |- $.
+$v ph $( again $) $.
+wph $f wff ph $.
+ax $a |- $( inside $) ph $.
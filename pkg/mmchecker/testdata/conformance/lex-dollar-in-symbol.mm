@@ -0,0 +1,3 @@
+$( expect: fail unexpected-token
+   Math symbols may not contain $. $)
+$c wff a$b $.
@@ -0,0 +1,5 @@
+$( expect: fail unexpected-token
+   Labels are made of letters, digits, hyphens, underscores and periods. $)
+$c wff $.
+$v ph $.
+w:ph $f wff ph $.
@@ -0,0 +1,4 @@
+$( expect: fail unexpected-token
+   Comments do not nest. $)
+$( outer $( inner $) $)
+$c wff $.
@@ -0,0 +1,4 @@
+$( expect: fail unexpected-token
+   $) only closes a comment. $)
+$c wff $.
+$)
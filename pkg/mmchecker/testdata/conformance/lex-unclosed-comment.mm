@@ -0,0 +1,4 @@
+$( expect: fail unclosed-comment
+   A comment must be closed. $)
+$c wff $.
+$( never closed
@@ -0,0 +1,4 @@
+$( expect: fail unexpected-token
+   Only the keywords of the specification exist. $)
+$c wff $.
+$x wff $.
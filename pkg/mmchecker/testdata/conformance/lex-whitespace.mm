@@ -0,0 +1,5 @@
+$( expect: pass
+   Tokens are separated by spaces, tabs, form feeds and line breaks. $)
+$c	wff|- $.
+$v ph $.
+wph	$f wff ph $.
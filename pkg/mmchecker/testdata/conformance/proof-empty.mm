@@ -0,0 +1,9 @@
+$( expect: fail malformed-proof
+   A proof has at least one step. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ph $= $.
@@ -0,0 +1,9 @@
+$( expect: fail conclusion-mismatch
+   A proof ends with exactly one entry on the stack. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ph $= wph wph $.
@@ -0,0 +1,17 @@
+$( expect: fail hypothesis-mismatch
+   Stack entries must match the hypotheses they are used for. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+${
+  h $e |- ph $.
+  th $p |- ps $= wph wps h h ax-mp $.
+$}
@@ -0,0 +1,18 @@
+$( expect: fail incomplete-proof
+   A ? step stands for an unknown statement, and leaves the proof incomplete. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+ax-1 $a |- ( ph -> ( ps -> ph ) ) $.
+${
+  h $e |- ph $.
+  th $p |- ( ps -> ph ) $= wph wps wph wi h ? ax-mp $.
+$}
@@ -0,0 +1,18 @@
+$( expect: pass
+   A normal proof lists its steps in reverse Polish notation. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  min $e |- ph $.
+  maj $e |- ( ph -> ps ) $.
+  ax-mp $a |- ps $.
+$}
+ax-1 $a |- ( ph -> ( ps -> ph ) ) $.
+${
+  h $e |- ph $.
+  th $p |- ( ps -> ph ) $= wph wps wph wi h wph wps ax-1 ax-mp $.
+$}
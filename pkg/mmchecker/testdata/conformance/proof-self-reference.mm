@@ -0,0 +1,9 @@
+$( expect: fail unknown-label
+   A theorem may not be used in its own proof. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ph $= th $.
@@ -0,0 +1,9 @@
+$( expect: fail stack-underflow
+   An assertion needs one stack entry for each mandatory hypothesis. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ( ph -> ps ) $= wph wi $.
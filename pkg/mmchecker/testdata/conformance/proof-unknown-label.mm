@@ -0,0 +1,9 @@
+$( expect: fail unknown-label
+   A proof may only use labels defined before it. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p |- ph $= nope $.
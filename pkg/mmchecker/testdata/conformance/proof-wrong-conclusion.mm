@@ -0,0 +1,9 @@
+$( expect: fail conclusion-mismatch
+   A proof ends with the statement it proves. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+th $p wff ph $= wps $.
@@ -0,0 +1,6 @@
+$( expect: fail unexpected-token
+   Constants are declared in the outermost block. $)
+$c wff $.
+${
+  $c |- $.
+$}
@@ -0,0 +1,4 @@
+$( expect: fail duplicate-symbol
+   A constant may only be declared once. $)
+$c wff $.
+$c wff $.
@@ -0,0 +1,9 @@
+$( expect: fail malformed-statement
+   A $d statement may not name a variable twice. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+$d ph ps ph $.
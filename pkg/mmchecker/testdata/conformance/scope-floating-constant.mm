@@ -0,0 +1,4 @@
+$( expect: fail undeclared-symbol
+   A $f statement types a variable, not a constant. $)
+$c wff |- $.
+w $f wff |- $.
@@ -0,0 +1,7 @@
+$( expect: fail duplicate-symbol
+   A variable may not become a constant, even once its scope has ended. $)
+$c wff $.
+${
+  $v ph $.
+$}
+$c ph $.
@@ -0,0 +1,8 @@
+$( expect: fail undeclared-symbol
+   A variable may not be used after its scope has ended. $)
+$c wff |- $.
+${
+  $v ph $.
+  wph $f wff ph $.
+$}
+ax $a |- ph $.
@@ -0,0 +1,9 @@
+$( expect: fail undeclared-symbol
+   Every symbol in an assertion must be declared. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+ax $a |- th $.
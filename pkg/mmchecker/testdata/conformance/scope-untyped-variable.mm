@@ -0,0 +1,5 @@
+$( expect: fail untyped-variable
+   Every variable in an assertion needs an active $f statement. $)
+$c wff |- $.
+$v ph $.
+ax $a |- ph $.
@@ -0,0 +1,12 @@
+$( expect: pass
+   A variable may be declared again once its scope has ended. $)
+$c wff |- $.
+${
+  $v ph $.
+  wph $f wff ph $.
+$}
+${
+  $v ph $.
+  wph2 $f wff ph $.
+  ax $a |- ph $.
+$}
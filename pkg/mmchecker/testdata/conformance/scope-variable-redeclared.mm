@@ -0,0 +1,7 @@
+$( expect: fail duplicate-symbol
+   An active variable may not be declared again. $)
+$c wff $.
+$v ph $.
+${
+  $v ph $.
+$}
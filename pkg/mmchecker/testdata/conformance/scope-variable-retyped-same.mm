@@ -0,0 +1,12 @@
+$( expect: pass
+   A variable declared again may be typed again with the same typecode. $)
+$c wff |- $.
+${
+  $v ph $.
+  wph $f wff ph $.
+$}
+${
+  $v ph $.
+  wph2 $f wff ph $.
+  ax $a |- ph $.
+$}
@@ -0,0 +1,10 @@
+$( expect: fail duplicate-symbol
+   A variable keeps the same typecode in every $f statement. $)
+$c wff set $.
+$v x $.
+${
+  wx $f wff x $.
+$}
+${
+  sx $f set x $.
+$}
@@ -0,0 +1,5 @@
+$( expect: fail duplicate-symbol
+   A symbol may not be both a variable and a constant. $)
+$c wff $.
+$v ph $.
+$c ph $.
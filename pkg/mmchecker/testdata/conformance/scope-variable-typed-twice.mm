@@ -0,0 +1,8 @@
+$( expect: fail duplicate-symbol
+   An active variable has only one active $f statement. $)
+$c wff set $.
+$v x $.
+wx $f wff x $.
+${
+  sx $f set x $.
+$}
@@ -0,0 +1,9 @@
+$( expect: fail malformed-statement
+   An assertion starts with a typecode. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+ax $a $.
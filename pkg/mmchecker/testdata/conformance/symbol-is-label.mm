@@ -0,0 +1,12 @@
+$( expect: fail duplicate-symbol
+   A math symbol may not match a label, even one whose scope has ended. $)
+$c ( ) -> wff |- $.
+$v ph ps ch $.
+wph $f wff ph $.
+wps $f wff ps $.
+wch $f wff ch $.
+wi $a wff ( ph -> ps ) $.
+${
+  h $e |- ph $.
+$}
+$c h $.
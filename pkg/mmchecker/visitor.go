@@ -0,0 +1,115 @@
+package mmchecker
+
+import "github.com/gregory-nisbet/mmchecker/pkg/internal/core"
+
+// ErrStop may be returned by a Visitor callback to end the read early without
+// reporting an error.
+var ErrStop = core.ErrStop
+
+// Visitor receives a callback for each part of a database as it is read, in source
+// order. Any other non-nil error returned from a callback aborts the read and is
+// returned, wrapped, from Validate or Load.
+//
+// Embed NopVisitor to implement only the callbacks you need.
+type Visitor interface {
+	OnConstant(symbol string) error
+	OnVariable(symbol string) error
+	OnHypothesis(hyp Hypothesis) error
+	OnAxiom(assertion Assertion) error
+	// result.Outcome says whether the proof was valid, invalid, incomplete or not
+	// checked.
+	OnTheorem(assertion Assertion, proof []string, result Statement) error
+	OnDisjoint(vars []string) error
+	OnScopeOpen() error
+	OnScopeClose() error
+	// text is the tokens between $( and $).
+	OnComment(text []string) error
+	// path is the absolute path of a newly included file.
+	OnInclude(path string) error
+}
+
+// Assertion is an axiom or theorem together with its mandatory hypotheses.
+type Assertion struct {
+	Label      string
+	Kind       Kind
+	Symbols    []string
+	Hypotheses []Hypothesis
+	Disjoint   []DisjointPair
+}
+
+// NopVisitor implements every Visitor callback by doing nothing.
+type NopVisitor struct{}
+
+func (NopVisitor) OnConstant(string) error                        { return nil }
+func (NopVisitor) OnVariable(string) error                        { return nil }
+func (NopVisitor) OnHypothesis(Hypothesis) error                  { return nil }
+func (NopVisitor) OnAxiom(Assertion) error                        { return nil }
+func (NopVisitor) OnTheorem(Assertion, []string, Statement) error { return nil }
+func (NopVisitor) OnDisjoint([]string) error                      { return nil }
+func (NopVisitor) OnScopeOpen() error                             { return nil }
+func (NopVisitor) OnScopeClose() error                            { return nil }
+func (NopVisitor) OnComment([]string) error                       { return nil }
+func (NopVisitor) OnInclude(string) error                         { return nil }
+
+// visitorAdapter translates core callbacks into public types.
+type visitorAdapter struct {
+	v Visitor
+}
+
+func (a visitorAdapter) OnConstant(tok string) error {
+	return a.v.OnConstant(tok)
+}
+
+func (a visitorAdapter) OnVariable(tok string) error {
+	return a.v.OnVariable(tok)
+}
+
+func (a visitorAdapter) OnHypothesis(label core.Label, stype string, stmt core.Stmt) error {
+	return a.v.OnHypothesis(Hypothesis{
+		Label:   string(label),
+		Kind:    Kind(stype),
+		Symbols: append([]string(nil), stmt...),
+	})
+}
+
+func (a visitorAdapter) OnAxiom(label core.Label, assertion *core.Assertion) error {
+	return a.v.OnAxiom(newAssertion(label, KindAxiom, assertion))
+}
+
+func (a visitorAdapter) OnTheorem(label core.Label, assertion *core.Assertion, proof []string, result core.Result) error {
+	return a.v.OnTheorem(
+		newAssertion(label, KindTheorem, assertion),
+		append([]string(nil), proof...),
+		newStatement(result),
+	)
+}
+
+func (a visitorAdapter) OnDisjoint(vars []string) error {
+	return a.v.OnDisjoint(append([]string(nil), vars...))
+}
+
+func (a visitorAdapter) OnScopeOpen() error {
+	return a.v.OnScopeOpen()
+}
+
+func (a visitorAdapter) OnScopeClose() error {
+	return a.v.OnScopeClose()
+}
+
+func (a visitorAdapter) OnComment(text []string) error {
+	return a.v.OnComment(append([]string(nil), text...))
+}
+
+func (a visitorAdapter) OnInclude(path string) error {
+	return a.v.OnInclude(path)
+}
+
+func newAssertion(label core.Label, kind Kind, assertion *core.Assertion) Assertion {
+	return Assertion{
+		Label:      string(label),
+		Kind:       kind,
+		Symbols:    append([]string(nil), assertion.S...),
+		Hypotheses: hypotheses(assertion),
+		Disjoint:   disjointPairs(assertion),
+	}
+}
@@ -0,0 +1,48 @@
+package mmchecker
+
+import (
+	"context"
+	"testing"
+)
+
+type theoremCollector struct {
+	NopVisitor
+	theorems []Assertion
+	outcomes []Outcome
+}
+
+func (c *theoremCollector) OnTheorem(assertion Assertion, _ []string, result Statement) error {
+	c.theorems = append(c.theorems, assertion)
+	c.outcomes = append(c.outcomes, result.Outcome)
+
+	return nil
+}
+
+// TestVisitor tests that a public visitor sees theorems with their hypotheses.
+func TestVisitor(t *testing.T) {
+	t.Parallel()
+
+	collector := &theoremCollector{}
+
+	_, err := ValidateWithConfig(context.Background(), "", tinyDatabase, Config{Visitor: collector})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := makeDiff(collector.theorems, []Assertion{{
+		Label:   "idi",
+		Kind:    KindTheorem,
+		Symbols: []string{"|-", "ph"},
+		Hypotheses: []Hypothesis{
+			{Label: "wph", Kind: KindFloating, Symbols: []string{"wff", "ph"}},
+			{Label: "idi.1", Kind: KindEssential, Symbols: []string{"|-", "ph"}},
+		},
+		Disjoint: []DisjointPair{},
+	}}); e != nil {
+		t.Error(e)
+	}
+
+	if e := makeDiff(collector.outcomes, []Outcome{OutcomeValid}); e != nil {
+		t.Error(e)
+	}
+}
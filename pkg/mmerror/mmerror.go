@@ -0,0 +1,220 @@
+// Package mmerror describes the errors reported while reading and verifying a
+// Metamath database.
+//
+// Every error has a Code that stays the same from release to release, so tools
+// can branch on the kind of problem instead of matching message text:
+//
+//	if errors.Is(err, mmerror.StackUnderflow) {
+//		...
+//	}
+//
+// The label and position of the statement at fault are available through
+// errors.As:
+//
+//	var e *mmerror.Error
+//	if errors.As(err, &e) {
+//		fmt.Println(e.Code, e.Label, e.Position)
+//	}
+package mmerror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code identifies a kind of error. Codes are also errors, so that errors.Is(err,
+// code) reports whether err is of that kind.
+type Code string
+
+// Error returns the code itself.
+func (c Code) Error() string {
+	return string(c)
+}
+
+// Codes for problems with the text of a database.
+const (
+	// UnclosedBlock is a ${ without a matching $}.
+	UnclosedBlock Code = "unclosed-block"
+	// UnclosedComment is a $( without a matching $).
+	UnclosedComment Code = "unclosed-comment"
+	// UnclosedStatement is a statement without its closing $. or $=.
+	UnclosedStatement Code = "unclosed-statement"
+	// UnexpectedToken is a token that cannot appear where it does, such as a
+	// stray $), a keyword that does not exist or a $c statement in an inner
+	// block.
+	UnexpectedToken Code = "unexpected-token"
+	// MalformedStatement is a statement with the wrong shape, such as a $f
+	// statement that is not a typecode followed by a variable.
+	MalformedStatement Code = "malformed-statement"
+	// MissingLabel is a $f, $e, $a or $p statement without a label.
+	MissingLabel Code = "missing-label"
+	// DuplicateLabel is a label that is defined twice.
+	DuplicateLabel Code = "duplicate-label"
+	// DuplicateSymbol is a constant or variable declared twice, a symbol
+	// declared both ways or also used as a label, or a variable typed by two
+	// active $f statements or by two different typecodes.
+	DuplicateSymbol Code = "duplicate-symbol"
+	// UndeclaredSymbol is a symbol that is not an active constant or
+	// variable where it is used.
+	UndeclaredSymbol Code = "undeclared-symbol"
+	// UntypedVariable is a variable with no active $f statement.
+	UntypedVariable Code = "untyped-variable"
+	// IncludeFailed is a $[ $] statement whose file cannot be read.
+	IncludeFailed Code = "include-failed"
+)
+
+// Codes for proofs that do not check.
+const (
+	// UnknownLabel is a proof step naming a label that does not exist yet.
+	UnknownLabel Code = "unknown-label"
+	// InactiveHypothesis is a proof step naming a hypothesis whose scope
+	// has ended.
+	InactiveHypothesis Code = "inactive-hypothesis"
+	// StackUnderflow is a proof step that needs more hypotheses than the
+	// proof stack holds.
+	StackUnderflow Code = "stack-underflow"
+	// HypothesisMismatch is a proof stack entry that does not match the
+	// hypothesis it is used for.
+	HypothesisMismatch Code = "hypothesis-mismatch"
+	// DisjointViolation is a substitution that breaks a $d restriction.
+	DisjointViolation Code = "disjoint-violation"
+	// ConclusionMismatch is a proof that does not end with exactly the
+	// statement it claims to prove.
+	ConclusionMismatch Code = "conclusion-mismatch"
+	// MalformedProof is a proof that cannot be decoded, such as an empty
+	// or badly compressed one.
+	MalformedProof Code = "malformed-proof"
+	// IncompleteProof is a proof with unknown steps, written ?, that is
+	// correct as far as it goes.
+	IncompleteProof Code = "incomplete-proof"
+)
+
+// Codes for runs that stop for reasons outside the database.
+const (
+	// LimitExceeded is a configured limit, such as the maximum proof
+	// length or the proof time limit, being reached.
+	LimitExceeded Code = "limit-exceeded"
+	// TooManyErrors is a recovering run that reached its maximum number of
+	// errors.
+	TooManyErrors Code = "too-many-errors"
+	// Canceled is a run stopped by its context being canceled or reaching
+	// its deadline.
+	Canceled Code = "canceled"
+	// IO is a failure to read a file.
+	IO Code = "io"
+	// Unknown is any other error.
+	Unknown Code = "unknown"
+)
+
+var descriptions = map[Code]string{
+	UnclosedBlock:      "A ${ block is not closed.",
+	UnclosedComment:    "A $( comment is not closed.",
+	UnclosedStatement:  "A statement is not closed.",
+	UnexpectedToken:    "A token appears where it is not allowed.",
+	MalformedStatement: "A statement does not have the required form.",
+	MissingLabel:       "A statement that needs a label has none.",
+	DuplicateLabel:     "A label is defined more than once.",
+	DuplicateSymbol:    "A symbol is declared or typed more than once.",
+	UndeclaredSymbol:   "A symbol is not an active constant or variable.",
+	UntypedVariable:    "A variable has no active $f statement.",
+	IncludeFailed:      "An included file cannot be read.",
+	UnknownLabel:       "A proof uses a label that does not exist.",
+	InactiveHypothesis: "A proof uses a hypothesis whose scope has ended.",
+	StackUnderflow:     "A proof step needs more hypotheses than are on the stack.",
+	HypothesisMismatch: "A proof stack entry does not match a hypothesis.",
+	DisjointViolation:  "A substitution violates a $d restriction.",
+	ConclusionMismatch: "A proof does not end with the statement it proves.",
+	MalformedProof:     "A proof cannot be decoded.",
+	IncompleteProof:    "A proof has unknown steps.",
+	LimitExceeded:      "A configured limit, such as the proof time limit, was exceeded.",
+	TooManyErrors:      "The maximum number of errors was reached.",
+	Canceled:           "The run was canceled or its deadline passed.",
+	IO:                 "A file cannot be read.",
+	Unknown:            "An error without a more specific code.",
+}
+
+// Description is a one-sentence summary of the kind of error c stands for.
+func (c Code) Description() string {
+	if d, ok := descriptions[c]; ok {
+		return d
+	}
+
+	return descriptions[Unknown]
+}
+
+// CodeOf returns the code of the first Error in the chain of err, Unknown if
+// there is none, or "" if err is nil.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return Unknown
+}
+
+// Position is a location in a database. File is empty for in-memory content.
+// Line and Column count from 1; Column is a byte offset within the line.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String formats the position as file:line:column, the form editors and
+// compilers use.
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Error is an error found in a database.
+type Error struct {
+	Code Code
+	// Label is the label of the statement at fault, if it has one.
+	Label string
+	// Position is where the problem was found, and LabelPosition where the
+	// statement's label starts. Either may be the zero Position.
+	Position      Position
+	LabelPosition Position
+	// Message describes the problem without its position.
+	Message string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error formats the error with its position and label.
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Position.IsValid() {
+		msg = e.Position.String() + ": " + msg
+	}
+
+	if e.Label != "" && e.LabelPosition.IsValid() {
+		msg += fmt.Sprintf(" (in statement %q starting at %s)", e.Label, e.LabelPosition)
+	}
+
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is e's Code.
+func (e *Error) Is(target error) bool {
+	return target == e.Code
+}
@@ -0,0 +1,51 @@
+package mmerror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestError tests formatting and matching of an Error.
+func TestError(t *testing.T) {
+	t.Parallel()
+
+	e := &Error{
+		Code:          UndeclaredSymbol,
+		Label:         "ax",
+		Position:      Position{File: "a.mm", Line: 2, Column: 11},
+		LabelPosition: Position{File: "a.mm", Line: 2, Column: 1},
+		Message:       `Token "x" is not an active symbol`,
+	}
+
+	want := `a.mm:2:11: Token "x" is not an active symbol (in statement "ax" starting at a.mm:2:1)`
+	if got := e.Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	wrapped := fmt.Errorf("reading: %w", e)
+	if !errors.Is(wrapped, UndeclaredSymbol) || errors.Is(wrapped, UnknownLabel) {
+		t.Errorf("errors.Is does not match the code of %v", wrapped)
+	}
+
+	if got := CodeOf(wrapped); got != UndeclaredSymbol {
+		t.Errorf("got code %q", got)
+	}
+}
+
+// TestCodeOf tests the codes of errors that are not an Error.
+func TestCodeOf(t *testing.T) {
+	t.Parallel()
+
+	if got := CodeOf(nil); got != "" {
+		t.Errorf("got %q for nil", got)
+	}
+
+	if got := CodeOf(errors.New("boom")); got != Unknown {
+		t.Errorf("got %q for a plain error", got)
+	}
+
+	if got := Code("no-such-code").Description(); got != Unknown.Description() {
+		t.Errorf("got description %q", got)
+	}
+}